@@ -0,0 +1,35 @@
+package nosqlite
+
+import "context"
+
+// Queryable is the set of read operations on a table, implemented by both *Table[T]
+// and *TableWithTx[T]
+type Queryable[T any] interface {
+	Count(ctx context.Context) (uint64, error)
+	QueryOne(ctx context.Context, clause Clause) (*T, error)
+	Get(ctx context.Context, clause Clause) (T, error)
+	QueryFirst(ctx context.Context, clause Clause, order ...Order) (*T, error)
+	QueryMany(ctx context.Context, clause Clause) ([]T, error)
+	All(ctx context.Context) ([]T, error)
+}
+
+// Writable is the set of write operations on a table, implemented by both *Table[T]
+// and *TableWithTx[T]
+type Writable[T any] interface {
+	Insert(ctx context.Context, data T) error
+	Update(ctx context.Context, clause Clause, newVal T) error
+	Delete(ctx context.Context, clause Clause) error
+}
+
+// TableAPI is the set of operations on a table, allowing code to work with a table
+// whether or not it is in a transaction, and allowing tables to be replaced with a
+// fake in tests
+type TableAPI[T any] interface {
+	Queryable[T]
+	Writable[T]
+}
+
+var (
+	_ TableAPI[any] = (*Table[any])(nil)
+	_ TableAPI[any] = (*TableWithTx[any])(nil)
+)
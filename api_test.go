@@ -0,0 +1,186 @@
+package nosqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+var (
+	_ TableAPI[Foo] = (*Table[Foo])(nil)
+	_ TableAPI[Foo] = (*TableWithTx[Foo])(nil)
+)
+
+// fakeFooTable is an in-memory TableAPI[Foo] that matches on name, as a downstream
+// user might write in their own tests
+type fakeFooTable struct {
+	items []Foo
+}
+
+var _ TableAPI[Foo] = (*fakeFooTable)(nil)
+
+func (f *fakeFooTable) Count(_ context.Context) (uint64, error) {
+	return uint64(len(f.items)), nil
+}
+
+func (f *fakeFooTable) Insert(_ context.Context, data Foo) error {
+	f.items = append(f.items, data)
+	return nil
+}
+
+func (f *fakeFooTable) QueryOne(ctx context.Context, clause Clause) (*Foo, error) {
+	return f.QueryFirst(ctx, clause)
+}
+
+func (f *fakeFooTable) Get(ctx context.Context, clause Clause) (Foo, error) {
+	v, _ := f.QueryOne(ctx, clause)
+	if v == nil {
+		return Foo{}, ErrNotFound
+	}
+	return *v, nil
+}
+
+func (f *fakeFooTable) QueryFirst(ctx context.Context, clause Clause, _ ...Order) (*Foo, error) {
+	items, _ := f.QueryMany(ctx, clause)
+	if len(items) == 0 {
+		return nil, nil
+	}
+	return &items[0], nil
+}
+
+func (f *fakeFooTable) QueryMany(_ context.Context, clause Clause) ([]Foo, error) {
+	var items []Foo
+	for _, item := range f.items {
+		if f.matches(clause, item) {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+func (f *fakeFooTable) All(ctx context.Context) ([]Foo, error) {
+	return f.QueryMany(ctx, All())
+}
+
+func (f *fakeFooTable) Update(_ context.Context, clause Clause, newVal Foo) error {
+	for i, item := range f.items {
+		if f.matches(clause, item) {
+			f.items[i] = newVal
+		}
+	}
+	return nil
+}
+
+func (f *fakeFooTable) Delete(_ context.Context, clause Clause) error {
+	var items []Foo
+	for _, item := range f.items {
+		if !f.matches(clause, item) {
+			items = append(items, item)
+		}
+	}
+	f.items = items
+	return nil
+}
+
+func (f *fakeFooTable) matches(clause Clause, item Foo) bool {
+	values := clause.Values()
+	return len(values) == 0 || values[0] == item.Name
+}
+
+// renameFoo is an example of code written against TableAPI
+func renameFoo(ctx context.Context, table TableAPI[Foo], from, to string) error {
+	foo, err := table.Get(ctx, Equal("$.name", from))
+	if err != nil {
+		return err
+	}
+	foo.Name = to
+	return table.Update(ctx, Equal("$.name", from), foo)
+}
+
+func TestTableAPI_Fake(t *testing.T) {
+	ctx := context.Background()
+
+	fake := &fakeFooTable{}
+
+	err := fake.Insert(ctx, Foo{Name: "before"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = renameFoo(ctx, fake, "before", "after")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fake.items[0].Name != "after" {
+		t.Errorf("expected after got %s", fake.items[0].Name)
+	}
+}
+
+func TestTableAPI_TableAndTableWithTx(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	implementations := []struct {
+		name string
+		run  func(fn func(TableAPI[Foo]) error) error
+	}{
+		{"table", func(fn func(TableAPI[Foo]) error) error {
+			return fn(table)
+		}},
+		{"tx", func(fn func(TableAPI[Foo]) error) error {
+			return store.WithTx(ctx, func(tx *Transaction) error {
+				return fn(table.WithTransaction(tx))
+			})
+		}},
+		{"fake", func(fn func(TableAPI[Foo]) error) error {
+			return fn(&fakeFooTable{})
+		}},
+	}
+
+	for _, impl := range implementations {
+		t.Run(impl.name, func(t *testing.T) {
+			from := impl.name + "-before"
+			to := impl.name + "-after"
+
+			err := impl.run(func(api TableAPI[Foo]) error {
+				err := api.Insert(ctx, Foo{Name: from})
+				if err != nil {
+					return err
+				}
+
+				err = renameFoo(ctx, api, from, to)
+				if err != nil {
+					return err
+				}
+
+				val, err := api.QueryOne(ctx, Equal("$.name", to))
+				if err != nil {
+					return err
+				}
+				if val == nil {
+					return fmt.Errorf("expected %s got nil", to)
+				}
+
+				err = api.Delete(ctx, Equal("$.name", to))
+				if err != nil {
+					return err
+				}
+
+				_, err = api.Get(ctx, Equal("$.name", to))
+				if !errors.Is(err, ErrNotFound) {
+					return fmt.Errorf("expected %v got %v", ErrNotFound, err)
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -1,8 +1,16 @@
 package nosqlite
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"golang.org/x/exp/constraints"
 )
@@ -17,6 +25,7 @@ var (
 	greaterThanOrEqualOperator operator = ">="
 	notEqualsOperator          operator = "!="
 	likeOperator               operator = "LIKE"
+	notLikeOperator            operator = "NOT LIKE"
 )
 
 type combinator string
@@ -37,12 +46,179 @@ type Clause interface {
 	// Values returns the values to assign to the parameters in the clause
 	Values() []any
 
+	// And returns And(receiver, c). Fluent chains associate to the left, so
+	// a.And(b).Or(c) is (a AND b) OR c. Nest calls, as in a.And(b.Or(c)), to group otherwise.
 	And(c Clause) Clause
+	// Or returns Or(receiver, c), associating to the left as with And
 	Or(c Clause) Clause
 }
 
+// driverValue converts v into a value the sqlite driver compares natively against
+// values extracted with ->>, falling back to its string representation
+func driverValue(v any) any {
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case string, bool, []byte:
+		return t
+	case int, int8, int16, int32, int64:
+		return t
+	case uint, uint8, uint16, uint32, uint64:
+		return t
+	case float32, float64:
+		return t
+	case time.Time:
+		return t.Format(time.RFC3339Nano)
+	}
+
+	// named types, such as enums declared as type Status int, by their underlying kind
+	// rather than their String method
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return rv.Uint()
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return rv.Bool()
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// jsonNumber converts numbers to int64 or float64 as SQLite reads them from a JSON
+// document, so that they compare equal to values extracted by json_each whatever their
+// Go type. Other values are converted with driverValue.
+func jsonNumber(v any) any {
+	switch t := v.(type) {
+	case int, int8, int16, int32, int64:
+		return reflect.ValueOf(t).Int()
+	case uint, uint8, uint16, uint32, uint64:
+		u := reflect.ValueOf(t).Uint()
+		if u > math.MaxInt64 {
+			// beyond int64 SQLite reads JSON integers as reals
+			return float64(u)
+		}
+		return int64(u)
+	case float32:
+		// encoding/json writes the shortest decimal for a float32, which differs from
+		// its widened float64 value
+		f, _ := strconv.ParseFloat(strconv.FormatFloat(float64(t), 'g', -1, 32), 64)
+		return f
+	}
+	return driverValue(v)
+}
+
+// ErrInvalidField is returned when a field path cannot be safely used in a query
+var ErrInvalidField = errors.New("invalid field path")
+
+// validateFieldPath rejects field paths that could break out of the quoted JSON path
+func validateFieldPath(field string) error {
+	for _, s := range []string{"'", "\"", "`", ";", "--", "/*", "*/"} {
+		if strings.Contains(field, s) {
+			return fmt.Errorf("%w: %q contains %q", ErrInvalidField, field, s)
+		}
+	}
+	return nil
+}
+
+// invalidClause is returned by clause constructors given arguments that cannot be used
+// in a query. Table operations return its error rather than executing it.
+type invalidClause struct {
+	err error
+}
+
+func (c *invalidClause) Clause() string {
+	return "FALSE"
+}
+
+func (c *invalidClause) Values() []any {
+	return nil
+}
+
+func (c *invalidClause) And(cl Clause) Clause {
+	return And(c, cl)
+}
+
+func (c *invalidClause) Or(cl Clause) Clause {
+	return Or(c, cl)
+}
+
+// validated returns c, or an invalidClause if field is not a valid field path
+func validated(field string, c Clause) Clause {
+	if err := validateFieldPath(field); err != nil {
+		return &invalidClause{err: err}
+	}
+	return c
+}
+
+// fieldClause is implemented by clauses that reference a single field of the document
+type fieldClause interface {
+	field() string
+}
+
+// Fields returns the fields referenced by c, in the order they first appear and without
+// duplicates, for example to pass to CreateIndex. Fields are returned as written, so "id"
+// and "$.id" are both returned if both are used. Fields within the sub clause of
+// ContainsWhere refer to array elements rather than the document and are not returned.
+func Fields(c Clause) []string {
+	var fields []string
+	var walk func(c Clause)
+	walk = func(c Clause) {
+		switch t := c.(type) {
+		case *combinatorClause:
+			for _, cl := range t.clauses {
+				walk(cl)
+			}
+		case fieldClause:
+			if !slices.Contains(fields, t.field()) {
+				fields = append(fields, t.field())
+			}
+		}
+	}
+	walk(c)
+	return fields
+}
+
+// clauseErr returns the error of the first invalid clause within c
+func clauseErr(c Clause) error {
+	switch t := c.(type) {
+	case *invalidClause:
+		return t.err
+	case *combinatorClause:
+		return t.err
+	case *containsWhereCondition:
+		return clauseErr(t.sub)
+	}
+	return nil
+}
+
+// jsonPath returns field as a JSON path, treating fields without a leading $ as
+// relative to the root of the document
+func jsonPath(field string) string {
+	if strings.HasPrefix(field, "$") {
+		return field
+	}
+	return "$." + field
+}
+
+// jsonField returns the expression extracting field from the document. Plain keys such
+// as "id" are extracted as labels, while dotted or indexed fields are extracted as JSON
+// paths so that "a.b.c" resolves nested objects rather than a key named "a.b.c", and
+// "list[0]" the first element of list.
 func jsonField(field string) string {
-	return fmt.Sprintf("data->>'%s'", field)
+	return jsonFieldOf("data", field)
+}
+
+// jsonFieldOf returns the expression extracting field from the document in column
+func jsonFieldOf(column, field string) string {
+	if strings.ContainsAny(field, ".[") {
+		field = jsonPath(field)
+	}
+	return fmt.Sprintf("%s->>'%s'", column, field)
 }
 
 type combinatorClause struct {
@@ -50,11 +226,12 @@ type combinatorClause struct {
 	clauses       []Clause
 	clauseStrings []string
 	values        []any
+	err           error
 }
 
 func (c *combinatorClause) Clause() string {
 	if len(c.clauses) == 0 {
-		return "(1 == 1)"
+		return "TRUE"
 	}
 	joiner := fmt.Sprintf(" %s ", string(c.combinator))
 
@@ -74,7 +251,29 @@ func (c *combinatorClause) Or(cl Clause) Clause {
 	return Or(c, cl)
 }
 
+// isEmptyClause returns true if c is a combinator with no clauses, such as All()
+func isEmptyClause(c Clause) bool {
+	cc, ok := c.(*combinatorClause)
+	return ok && len(cc.clauses) == 0
+}
+
 func combine(combinator combinator, clauses ...Clause) Clause {
+	var errs []error
+	for _, clause := range clauses {
+		errs = append(errs, clauseErr(clause))
+	}
+
+	// empty clauses are always true so add nothing to an AND, and make an OR always true
+	nonEmpty := make([]Clause, 0, len(clauses))
+	for _, clause := range clauses {
+		if !isEmptyClause(clause) {
+			nonEmpty = append(nonEmpty, clause)
+		} else if combinator == orCombinator {
+			return &combinatorClause{combinator: combinator, err: errors.Join(errs...)}
+		}
+	}
+	clauses = nonEmpty
+
 	clauseStrings := make([]string, len(clauses))
 	for i, clause := range clauses {
 		clauseStrings[i] = clause.Clause()
@@ -90,6 +289,7 @@ func combine(combinator combinator, clauses ...Clause) Clause {
 		clauses:       clauses,
 		clauseStrings: clauseStrings,
 		values:        values,
+		err:           errors.Join(errs...),
 	}
 }
 
@@ -103,6 +303,12 @@ func Or(clauses ...Clause) Clause {
 	return combine(orCombinator, clauses...)
 }
 
+// Group returns a clause that wraps c in parentheses. Every clause is already
+// parenthesized, so Group only serves to make intended precedence explicit.
+func Group(c Clause) Clause {
+	return And(c)
+}
+
 type condition[T string | number] struct {
 	Field    string
 	Value    T
@@ -114,7 +320,7 @@ func (c *condition[T]) Clause() string {
 }
 
 func (c *condition[T]) Values() []any {
-	return []any{fmt.Sprintf("%v", c.Value)}
+	return []any{driverValue(c.Value)}
 }
 
 func (c *condition[T]) And(cl Clause) Clause {
@@ -125,44 +331,190 @@ func (c *condition[T]) Or(cl Clause) Clause {
 	return Or(c, cl)
 }
 
+func (c *condition[T]) field() string {
+	return c.Field
+}
+
 // Equal returns a clause that checks if a field is equal to a value
 func Equal[T string | number](field string, value T) Clause {
-	return &condition[T]{Field: field, Value: value, Operator: equalsOperator}
+	return validated(field, &condition[T]{Field: field, Value: value, Operator: equalsOperator})
+}
+
+// BytesEqual returns a clause that checks if a []byte field is equal to b. encoding/json
+// stores []byte as a base64 string, so b is encoded the same way before comparing.
+func BytesEqual(field string, b []byte) Clause {
+	return Equal(field, base64.StdEncoding.EncodeToString(b))
 }
 
 // LessThan returns a clause that checks if a field is less than a value
 func LessThan[T string | number](field string, value T) Clause {
-	return &condition[T]{Field: field, Value: value, Operator: lessThanOperator}
+	return validated(field, &condition[T]{Field: field, Value: value, Operator: lessThanOperator})
 }
 
 // GreaterThan returns a clause that checks if a field is greater than a value
 func GreaterThan[T string | number](field string, value T) Clause {
-	return &condition[T]{Field: field, Value: value, Operator: greaterThanOperator}
+	return validated(field, &condition[T]{Field: field, Value: value, Operator: greaterThanOperator})
 }
 
 // LessThanOrEqual returns a clause that checks if a field is less than or equal to a value
 func LessThanOrEqual[T string | number](field string, value T) Clause {
-	return &condition[T]{Field: field, Value: value, Operator: lessThanOrEqualOperator}
+	return validated(field, &condition[T]{Field: field, Value: value, Operator: lessThanOrEqualOperator})
 }
 
 // GreaterThanOrEqual returns a clause that checks if a field is greater than or equal to a value
 func GreaterThanOrEqual[T string | number](field string, value T) Clause {
-	return &condition[T]{Field: field, Value: value, Operator: greaterThanOrEqualOperator}
+	return validated(field, &condition[T]{Field: field, Value: value, Operator: greaterThanOrEqualOperator})
 }
 
+// All returns a clause that matches every item
 func All() Clause {
 	return And()
 }
 
+type noneClause struct{}
+
+func (c *noneClause) Clause() string {
+	return "FALSE"
+}
+
+func (c *noneClause) Values() []any {
+	return []any{}
+}
+
+func (c *noneClause) And(cl Clause) Clause {
+	return And(c, cl)
+}
+
+func (c *noneClause) Or(cl Clause) Clause {
+	return Or(c, cl)
+}
+
+// None returns a clause that matches no items
+func None() Clause {
+	return &noneClause{}
+}
+
+// AnyOf returns a clause that checks if a field is equal to any of values
+// With no values it matches no items
+func AnyOf[T string | number](field string, values ...T) Clause {
+	if len(values) == 0 {
+		return validated(field, None())
+	}
+
+	clauses := make([]Clause, len(values))
+	for i, value := range values {
+		clauses[i] = Equal(field, value)
+	}
+	return Or(clauses...)
+}
+
 // NotEqual returns a clause that checks if a field is not equal to a value
 func NotEqual[T string | number](field string, value T) Clause {
-	return &condition[T]{Field: field, Value: value, Operator: notEqualsOperator}
+	return validated(field, &condition[T]{Field: field, Value: value, Operator: notEqualsOperator})
 }
 
 // Like returns a clause that checks if a field is like a value
 // It's up to the user to add the requisite % characters
 func Like(field string, value string) Clause {
-	return &condition[string]{Field: field, Value: value, Operator: likeOperator}
+	return validated(field, &condition[string]{Field: field, Value: value, Operator: likeOperator})
+}
+
+// NotLike returns a clause that checks if a field is not like a value, the complement of Like
+// for items that have the field. Items without the field match neither.
+func NotLike(field string, value string) Clause {
+	return validated(field, &condition[string]{Field: field, Value: value, Operator: notLikeOperator})
+}
+
+// likeEscapeChar is the escape character used by EscapeLike
+const likeEscapeChar = `\`
+
+var likeEscaper = strings.NewReplacer(
+	likeEscapeChar, likeEscapeChar+likeEscapeChar,
+	"%", likeEscapeChar+"%",
+	"_", likeEscapeChar+"_",
+)
+
+// EscapeLike escapes the LIKE wildcards % and _ in s, along with the escape character
+// itself, so that s matches literally in clauses that escape with a backslash
+func EscapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+type likeCondition struct {
+	Field   string
+	Pattern string
+}
+
+func (c *likeCondition) Clause() string {
+	return fmt.Sprintf("(%s LIKE ? ESCAPE '%s')", jsonField(c.Field), likeEscapeChar)
+}
+
+func (c *likeCondition) Values() []any {
+	return []any{c.Pattern}
+}
+
+func (c *likeCondition) And(cl Clause) Clause {
+	return And(c, cl)
+}
+
+func (c *likeCondition) Or(cl Clause) Clause {
+	return Or(c, cl)
+}
+
+func (c *likeCondition) field() string {
+	return c.Field
+}
+
+// StartsWith returns a clause that checks if a field starts with prefix
+// Wildcards in prefix are escaped
+func StartsWith(field string, prefix string) Clause {
+	return validated(field, &likeCondition{Field: field, Pattern: EscapeLike(prefix) + "%"})
+}
+
+// EndsWith returns a clause that checks if a field ends with suffix
+// Wildcards in suffix are escaped
+func EndsWith(field string, suffix string) Clause {
+	return validated(field, &likeCondition{Field: field, Pattern: "%" + EscapeLike(suffix)})
+}
+
+// StringContains returns a clause that checks if a string field contains substr
+// Wildcards in substr are escaped. Unlike Contains, which matches elements of an
+// array field, this matches a substring of a single string value. As with LIKE the
+// match is case-insensitive for ASCII characters.
+func StringContains(field string, substr string) Clause {
+	return validated(field, &likeCondition{Field: field, Pattern: "%" + EscapeLike(substr) + "%"})
+}
+
+type likeEscapeCondition struct {
+	Field   string
+	Pattern string
+	Escape  rune
+}
+
+func (c *likeEscapeCondition) Clause() string {
+	return fmt.Sprintf("(%s LIKE ? ESCAPE ?)", jsonField(c.Field))
+}
+
+func (c *likeEscapeCondition) Values() []any {
+	return []any{c.Pattern, string(c.Escape)}
+}
+
+func (c *likeEscapeCondition) And(cl Clause) Clause {
+	return And(c, cl)
+}
+
+func (c *likeEscapeCondition) Or(cl Clause) Clause {
+	return Or(c, cl)
+}
+
+func (c *likeEscapeCondition) field() string {
+	return c.Field
+}
+
+// LikeEscape returns a clause that checks if a field is like pattern, where escape precedes
+// a % or _ in pattern that should match literally, e.g. LikeEscape("$.discount", "50!%", '!')
+func LikeEscape(field string, pattern string, escape rune) Clause {
+	return validated(field, &likeEscapeCondition{Field: field, Pattern: pattern, Escape: escape})
 }
 
 type inCondition struct {
@@ -195,9 +547,31 @@ func (c *inCondition) Or(cl Clause) Clause {
 	return Or(c, cl)
 }
 
+func (c *inCondition) field() string {
+	return c.Field
+}
+
 // In returns a clause that checks if a field is in a list of values
 func In(field string, values ...any) Clause {
-	return &inCondition{Field: field, values: values}
+	driverValues := make([]any, len(values))
+	for i, v := range values {
+		driverValues[i] = driverValue(v)
+	}
+	return validated(field, &inCondition{Field: field, values: driverValues})
+}
+
+// CompositeKey returns a clause that checks if each of fields is equal to the value at the
+// same position in values, identifying items by several fields at once
+func CompositeKey(fields []string, values ...any) Clause {
+	if len(fields) != len(values) {
+		return &invalidClause{err: fmt.Errorf("composite key has %d fields but %d values", len(fields), len(values))}
+	}
+
+	clauses := make([]Clause, len(fields))
+	for i, field := range fields {
+		clauses[i] = In(field, values[i])
+	}
+	return And(clauses...)
 }
 
 type betweenCondition[T string | number] struct {
@@ -222,15 +596,20 @@ func (c *betweenCondition[T]) Or(cl Clause) Clause {
 	return Or(c, cl)
 }
 
+func (c *betweenCondition[T]) field() string {
+	return c.Field
+}
+
 // Between returns a clause that checks if a field is between two values
 func Between[T string | number](field string, from, to T) Clause {
-	return &betweenCondition[T]{Field: field, From: from, To: to}
+	return validated(field, &betweenCondition[T]{Field: field, From: from, To: to})
 }
 
 type containsCondition struct {
 	Field      string
 	combinator combinator
 	values     []any
+	negated    bool
 }
 
 func (c *containsCondition) singleClause() string {
@@ -238,14 +617,18 @@ func (c *containsCondition) singleClause() string {
 }
 
 func (c *containsCondition) Clause() string {
-	if len(c.values) == 1 {
-		return c.singleClause()
+	clause := c.singleClause()
+	if len(c.values) != 1 {
+		clauses := make([]string, len(c.values))
+		for i := range c.values {
+			clauses[i] = c.singleClause()
+		}
+		clause = fmt.Sprintf("(%s)", strings.Join(clauses, fmt.Sprintf(" %s ", c.combinator)))
 	}
-	clauses := make([]string, len(c.values))
-	for i := range c.values {
-		clauses[i] = c.singleClause()
+	if c.negated {
+		return fmt.Sprintf("(NOT %s)", clause)
 	}
-	return fmt.Sprintf("(%s)", strings.Join(clauses, fmt.Sprintf(" %s ", c.combinator)))
+	return clause
 }
 
 func (c *containsCondition) Values() []any {
@@ -260,7 +643,12 @@ func (c *containsCondition) Or(cl Clause) Clause {
 	return Or(c, cl)
 }
 
+func (c *containsCondition) field() string {
+	return c.Field
+}
+
 // Contains returns a clause that checks if a list field contains a single value
+// Use StringContains to match a substring of a string field
 func Contains[T string | number](field string, value T) Clause {
 	return ContainsAll(field, value)
 }
@@ -276,9 +664,9 @@ func orCondition[T string | number](field string, values []T) Clause {
 func newContainsCondition[T string | number](field string, combinator combinator, values []T) Clause {
 	anyValues := make([]any, len(values))
 	for i, tag := range values {
-		anyValues[i] = tag
+		anyValues[i] = jsonNumber(tag)
 	}
-	return &containsCondition{Field: field, combinator: combinator, values: anyValues}
+	return validated(field, &containsCondition{Field: field, combinator: combinator, values: anyValues})
 }
 
 func ContainsAll[T string | number](field string, values ...T) Clause {
@@ -288,3 +676,470 @@ func ContainsAll[T string | number](field string, values ...T) Clause {
 func ContainsAny[T string | number](field string, values ...T) Clause {
 	return orCondition(field, values)
 }
+
+// NotContainsAll returns a clause that checks if a list field is missing at least one of values
+// With no values there is nothing to be missing so it matches no items
+func NotContainsAll[T string | number](field string, values ...T) Clause {
+	if len(values) == 0 {
+		return validated(field, None())
+	}
+	return negateContains(andCondition(field, values))
+}
+
+// NotContainsAny returns a clause that checks if a list field contains none of values
+// With no values it matches every item
+func NotContainsAny[T string | number](field string, values ...T) Clause {
+	if len(values) == 0 {
+		return validated(field, All())
+	}
+	return negateContains(orCondition(field, values))
+}
+
+// negateContains negates c if it is a valid containsCondition
+func negateContains(c Clause) Clause {
+	if cc, ok := c.(*containsCondition); ok {
+		cc.negated = true
+	}
+	return c
+}
+
+type containsWhereCondition struct {
+	Field string
+	sub   Clause
+}
+
+// Clause aliases each element of the array as data so that sub, which is written
+// against data, is evaluated against the element rather than the document. The array
+// is extracted in its own subquery as otherwise data in the json_each argument would
+// resolve to the alias rather than the document.
+func (c *containsWhereCondition) Clause() string {
+	return fmt.Sprintf("(EXISTS (SELECT 1 FROM (SELECT value AS data FROM (SELECT %s AS list), json_each(list)) WHERE %s))", jsonField(c.Field), c.sub.Clause())
+}
+
+func (c *containsWhereCondition) Values() []any {
+	return c.sub.Values()
+}
+
+func (c *containsWhereCondition) And(cl Clause) Clause {
+	return And(c, cl)
+}
+
+func (c *containsWhereCondition) Or(cl Clause) Clause {
+	return Or(c, cl)
+}
+
+func (c *containsWhereCondition) field() string {
+	return c.Field
+}
+
+// ContainsWhere returns a clause that checks if a list field contains an object matching sub.
+// Fields in sub are relative to each element, e.g.
+// ContainsWhere("$.addresses", Equal("$.city", "London"))
+func ContainsWhere(field string, sub Clause) Clause {
+	return validated(field, &containsWhereCondition{Field: field, sub: sub})
+}
+
+type hasKeyCondition struct {
+	Field string
+}
+
+func (c *hasKeyCondition) Clause() string {
+	return fmt.Sprintf("(json_type(data, '%s') IS NOT NULL)", jsonPath(c.Field))
+}
+
+func (c *hasKeyCondition) Values() []any {
+	return []any{}
+}
+
+func (c *hasKeyCondition) And(cl Clause) Clause {
+	return And(c, cl)
+}
+
+func (c *hasKeyCondition) Or(cl Clause) Clause {
+	return Or(c, cl)
+}
+
+func (c *hasKeyCondition) field() string {
+	return c.Field
+}
+
+// HasKey returns a clause that checks if a field is present, including when its value is null
+func HasKey(field string) Clause {
+	return validated(field, &hasKeyCondition{Field: field})
+}
+
+type arrayEmptyCondition struct {
+	Field   string
+	negated bool
+}
+
+func (c *arrayEmptyCondition) Clause() string {
+	operator := equalsOperator
+	if c.negated {
+		operator = greaterThanOperator
+	}
+	return fmt.Sprintf("(COALESCE(json_array_length(data, '%s'), 0) %s 0)", jsonPath(c.Field), operator)
+}
+
+func (c *arrayEmptyCondition) Values() []any {
+	return []any{}
+}
+
+func (c *arrayEmptyCondition) And(cl Clause) Clause {
+	return And(c, cl)
+}
+
+func (c *arrayEmptyCondition) Or(cl Clause) Clause {
+	return Or(c, cl)
+}
+
+func (c *arrayEmptyCondition) field() string {
+	return c.Field
+}
+
+// ArrayEmpty returns a clause that checks if an array field has no elements, treating absent,
+// null and non-array fields as empty
+func ArrayEmpty(field string) Clause {
+	return validated(field, &arrayEmptyCondition{Field: field})
+}
+
+// ArrayNotEmpty returns a clause that checks if an array field has at least one element
+func ArrayNotEmpty(field string) Clause {
+	return validated(field, &arrayEmptyCondition{Field: field, negated: true})
+}
+
+// JSONType is a type of JSON value as reported by json_type
+type JSONType string
+
+const (
+	JSONNull    JSONType = "null"
+	JSONTrue    JSONType = "true"
+	JSONFalse   JSONType = "false"
+	JSONInteger JSONType = "integer"
+	JSONReal    JSONType = "real"
+	JSONString  JSONType = "text"
+	JSONArray   JSONType = "array"
+	JSONObject  JSONType = "object"
+)
+
+type isTypeCondition struct {
+	Field string
+	Type  JSONType
+}
+
+func (c *isTypeCondition) Clause() string {
+	return fmt.Sprintf("(json_type(data, '%s') = ?)", jsonPath(c.Field))
+}
+
+func (c *isTypeCondition) Values() []any {
+	return []any{string(c.Type)}
+}
+
+func (c *isTypeCondition) And(cl Clause) Clause {
+	return And(c, cl)
+}
+
+func (c *isTypeCondition) Or(cl Clause) Clause {
+	return Or(c, cl)
+}
+
+func (c *isTypeCondition) field() string {
+	return c.Field
+}
+
+// IsType returns a clause that checks if a field holds a JSON value of type t
+// Absent fields never match, use JSONNull to match fields present with a null value
+func IsType(field string, t JSONType) Clause {
+	return validated(field, &isTypeCondition{Field: field, Type: t})
+}
+
+type numericCondition[T number] struct {
+	Field    string
+	Value    T
+	Operator operator
+}
+
+func (c *numericCondition[T]) Clause() string {
+	return fmt.Sprintf("(CAST(%s AS REAL) %s ?)", jsonField(c.Field), c.Operator)
+}
+
+func (c *numericCondition[T]) Values() []any {
+	return []any{driverValue(c.Value)}
+}
+
+func (c *numericCondition[T]) And(cl Clause) Clause {
+	return And(c, cl)
+}
+
+func (c *numericCondition[T]) Or(cl Clause) Clause {
+	return Or(c, cl)
+}
+
+func (c *numericCondition[T]) field() string {
+	return c.Field
+}
+
+// NumericEqual returns a clause that checks if a field, converted to a number, is equal to a value
+// The Numeric clauses compare numbers stored as strings numerically rather than lexicographically
+func NumericEqual[T number](field string, value T) Clause {
+	return validated(field, &numericCondition[T]{Field: field, Value: value, Operator: equalsOperator})
+}
+
+// NumericLessThan returns a clause that checks if a field, converted to a number, is less than a value
+func NumericLessThan[T number](field string, value T) Clause {
+	return validated(field, &numericCondition[T]{Field: field, Value: value, Operator: lessThanOperator})
+}
+
+// NumericGreaterThan returns a clause that checks if a field, converted to a number, is greater than a value
+func NumericGreaterThan[T number](field string, value T) Clause {
+	return validated(field, &numericCondition[T]{Field: field, Value: value, Operator: greaterThanOperator})
+}
+
+// NumericLessThanOrEqual returns a clause that checks if a field, converted to a number, is less than or equal to a value
+func NumericLessThanOrEqual[T number](field string, value T) Clause {
+	return validated(field, &numericCondition[T]{Field: field, Value: value, Operator: lessThanOrEqualOperator})
+}
+
+// NumericGreaterThanOrEqual returns a clause that checks if a field, converted to a number, is greater than or equal to a value
+func NumericGreaterThanOrEqual[T number](field string, value T) Clause {
+	return validated(field, &numericCondition[T]{Field: field, Value: value, Operator: greaterThanOrEqualOperator})
+}
+
+type timeCondition struct {
+	Field    string
+	Value    time.Time
+	Operator operator
+}
+
+// Clause compares instants rather than strings, so times stored with different offsets
+// or numbers of fractional digits are ordered correctly
+func (c *timeCondition) Clause() string {
+	return fmt.Sprintf("(unixepoch(%s, 'subsec') %s unixepoch(?, 'subsec'))", jsonField(c.Field), c.Operator)
+}
+
+func (c *timeCondition) Values() []any {
+	return []any{c.Value.UTC().Format(time.RFC3339Nano)}
+}
+
+func (c *timeCondition) And(cl Clause) Clause {
+	return And(c, cl)
+}
+
+func (c *timeCondition) Or(cl Clause) Clause {
+	return Or(c, cl)
+}
+
+func (c *timeCondition) field() string {
+	return c.Field
+}
+
+// After returns a clause that checks if a time field, stored as by encoding/json, is after t
+// Times are compared to millisecond precision
+func After(field string, t time.Time) Clause {
+	return validated(field, &timeCondition{Field: field, Value: t, Operator: greaterThanOperator})
+}
+
+// Before returns a clause that checks if a time field, stored as by encoding/json, is before t
+// Times are compared to millisecond precision
+func Before(field string, t time.Time) Clause {
+	return validated(field, &timeCondition{Field: field, Value: t, Operator: lessThanOperator})
+}
+
+type timeBetweenCondition struct {
+	Field string
+	From  time.Time
+	To    time.Time
+}
+
+func (c *timeBetweenCondition) Clause() string {
+	return fmt.Sprintf("(unixepoch(%s, 'subsec') BETWEEN unixepoch(?, 'subsec') AND unixepoch(?, 'subsec'))", jsonField(c.Field))
+}
+
+func (c *timeBetweenCondition) Values() []any {
+	return []any{c.From.UTC().Format(time.RFC3339Nano), c.To.UTC().Format(time.RFC3339Nano)}
+}
+
+func (c *timeBetweenCondition) And(cl Clause) Clause {
+	return And(c, cl)
+}
+
+func (c *timeBetweenCondition) Or(cl Clause) Clause {
+	return Or(c, cl)
+}
+
+func (c *timeBetweenCondition) field() string {
+	return c.Field
+}
+
+// BetweenTime returns a clause that checks if a time field, stored as by encoding/json, is
+// between from and to inclusive. Times are compared to millisecond precision
+func BetweenTime(field string, from, to time.Time) Clause {
+	return validated(field, &timeBetweenCondition{Field: field, From: from, To: to})
+}
+
+// elementOperators are the operators accepted by AnyElement and AllElements
+var elementOperators = []operator{equalsOperator, notEqualsOperator, lessThanOperator, greaterThanOperator, lessThanOrEqualOperator, greaterThanOrEqualOperator}
+
+type elementCondition[T string | number] struct {
+	Field    string
+	Value    T
+	Operator operator
+	all      bool
+}
+
+func (c *elementCondition[T]) Clause() string {
+	if c.all {
+		return fmt.Sprintf("(%s IS NOT NULL AND NOT EXISTS (SELECT 1 FROM json_each(%s) WHERE NOT (json_each.value %s ?)))", jsonField(c.Field), jsonField(c.Field), c.Operator)
+	}
+	return fmt.Sprintf("(EXISTS (SELECT 1 FROM json_each(%s) WHERE json_each.value %s ?))", jsonField(c.Field), c.Operator)
+}
+
+func (c *elementCondition[T]) Values() []any {
+	return []any{jsonNumber(c.Value)}
+}
+
+func (c *elementCondition[T]) And(cl Clause) Clause {
+	return And(c, cl)
+}
+
+func (c *elementCondition[T]) Or(cl Clause) Clause {
+	return Or(c, cl)
+}
+
+func (c *elementCondition[T]) field() string {
+	return c.Field
+}
+
+func newElementCondition[T string | number](field string, op string, value T, all bool) Clause {
+	if !slices.Contains(elementOperators, operator(op)) {
+		return &invalidClause{err: fmt.Errorf("unsupported element operator %q", op)}
+	}
+	return validated(field, &elementCondition[T]{Field: field, Value: value, Operator: operator(op), all: all})
+}
+
+// AnyElement returns a clause that checks if any element of a list field compares to value
+// with op, one of "=", "!=", "<", ">", "<=" or ">=", e.g. AnyElement("$.scores", ">", 90)
+func AnyElement[T string | number](field string, op string, value T) Clause {
+	return newElementCondition(field, op, value, false)
+}
+
+// AllElements returns a clause that checks if every element of a list field compares to value
+// with op, as in AnyElement. Empty lists match, documents without the field do not.
+func AllElements[T string | number](field string, op string, value T) Clause {
+	return newElementCondition(field, op, value, true)
+}
+
+type isCondition struct {
+	Field string
+	Value any
+}
+
+func (c *isCondition) Clause() string {
+	return fmt.Sprintf("(%s IS ?)", jsonField(c.Field))
+}
+
+func (c *isCondition) Values() []any {
+	return []any{driverValue(c.Value)}
+}
+
+func (c *isCondition) And(cl Clause) Clause {
+	return And(c, cl)
+}
+
+func (c *isCondition) Or(cl Clause) Clause {
+	return Or(c, cl)
+}
+
+func (c *isCondition) field() string {
+	return c.Field
+}
+
+// Is returns a clause that checks if a field is equal to a value, treating NULL as equal to
+// NULL so that Is(field, nil) matches documents where the field is null or missing
+func Is(field string, value any) Clause {
+	return validated(field, &isCondition{Field: field, Value: value})
+}
+
+type jsonEqualCondition struct {
+	Field string
+	Value string
+}
+
+// Clause extracts both sides with json_extract so that scalars are compared as SQL values and
+// objects and arrays as their minified JSON text, rather than the comparand being bound as is
+func (c *jsonEqualCondition) Clause() string {
+	return fmt.Sprintf("(json_extract(data, '%s') = json_extract(?, '$'))", jsonPath(c.Field))
+}
+
+func (c *jsonEqualCondition) Values() []any {
+	return []any{c.Value}
+}
+
+func (c *jsonEqualCondition) And(cl Clause) Clause {
+	return And(c, cl)
+}
+
+func (c *jsonEqualCondition) Or(cl Clause) Clause {
+	return Or(c, cl)
+}
+
+func (c *jsonEqualCondition) field() string {
+	return c.Field
+}
+
+// JSONEqual returns a clause that checks if a field is equal to value once encoded with
+// encoding/json, e.g. JSONEqual("$.address", Address{City: "London"}) or
+// JSONEqual("$.tags", []string{"a", "b"}). Unlike Equal, value can be an object, array or
+// bool. Objects only match if their keys are in the same order as the stored document.
+func JSONEqual(field string, value any) Clause {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return &invalidClause{err: err}
+	}
+	return validated(field, &jsonEqualCondition{Field: field, Value: string(b)})
+}
+
+type rawClause struct {
+	sql    string
+	values []any
+}
+
+func (c *rawClause) Clause() string {
+	return fmt.Sprintf("(%s)", c.sql)
+}
+
+func (c *rawClause) Values() []any {
+	return c.values
+}
+
+func (c *rawClause) And(cl Clause) Clause {
+	return And(c, cl)
+}
+
+func (c *rawClause) Or(cl Clause) Clause {
+	return Or(c, cl)
+}
+
+// Raw returns a clause of arbitrary SQL with a ? parameter for each of values, for conditions
+// the other clauses cannot express such as calls to functions added with Store.RegisterFunc.
+// The document is available as data, e.g. Raw("semver_lt(data->>'version', ?)", "1.2.0").
+// sql is used as given so must never be built from untrusted input.
+func Raw(sql string, values ...any) Clause {
+	driverValues := make([]any, len(values))
+	for i, v := range values {
+		driverValues[i] = driverValue(v)
+	}
+	return &rawClause{sql: sql, values: driverValues}
+}
+
+// AfterUnix returns a clause that checks if a time field, stored as Unix milliseconds such
+// as by time.Time.UnixMilli, is after t. Unlike After the comparison can use an index on field.
+func AfterUnix(field string, t time.Time) Clause {
+	return GreaterThan(field, t.UnixMilli())
+}
+
+// BeforeUnix returns a clause that checks if a time field, stored as Unix milliseconds such
+// as by time.Time.UnixMilli, is before t. Unlike Before the comparison can use an index on field.
+func BeforeUnix(field string, t time.Time) Clause {
+	return LessThan(field, t.UnixMilli())
+}
@@ -1,7 +1,11 @@
 package nosqlite
 
 import (
+	"errors"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestInClause(t *testing.T) {
@@ -49,8 +53,8 @@ func TestAndClauses(t *testing.T) {
 		t.Errorf("got = %v, want %v", got, want)
 	}
 
-	if got := c.Values(); got[0] != "1" || got[1] != "test" {
-		t.Errorf("got = %v, want %v", got, []string{"1", "test"})
+	if got := c.Values(); got[0] != 1 || got[1] != "test" {
+		t.Errorf("got = %v, want %v", got, []any{1, "test"})
 	}
 }
 
@@ -73,8 +77,8 @@ func TestAndClausesFluent(t *testing.T) {
 		t.Errorf("got = %v, want %v", got, want)
 	}
 
-	if got := c.Values(); got[0] != "1" || got[1] != "test" {
-		t.Errorf("got = %v, want %v", got, []string{"1", "test"})
+	if got := c.Values(); got[0] != 1 || got[1] != "test" {
+		t.Errorf("got = %v, want %v", got, []any{1, "test"})
 	}
 }
 
@@ -97,8 +101,8 @@ func TestOrClauses(t *testing.T) {
 		t.Errorf("got = %v, want %v", got, want)
 	}
 
-	if got := c.Values(); got[0] != "1" || got[1] != "test" {
-		t.Errorf("got = %v, want %v", got, []string{"1", "test"})
+	if got := c.Values(); got[0] != 1 || got[1] != "test" {
+		t.Errorf("got = %v, want %v", got, []any{1, "test"})
 	}
 }
 
@@ -121,8 +125,8 @@ func TestOrClausesFluent(t *testing.T) {
 		t.Errorf("got = %v, want %v", got, want)
 	}
 
-	if got := c.Values(); got[0] != "1" || got[1] != "test" {
-		t.Errorf("got = %v, want %v", got, []string{"1", "test"})
+	if got := c.Values(); got[0] != 1 || got[1] != "test" {
+		t.Errorf("got = %v, want %v", got, []any{1, "test"})
 	}
 }
 
@@ -151,8 +155,8 @@ func TestAndOrClauses(t *testing.T) {
 		t.Errorf("got %v, want %v", got, want)
 	}
 
-	if got := c2.Values(); got[0] != "1" || got[1] != "test" || got[2] != "bar" {
-		t.Errorf("got %v, want %v", got, []string{"1", "test", "bar"})
+	if got := c2.Values(); got[0] != 1 || got[1] != "test" || got[2] != "bar" {
+		t.Errorf("got %v, want %v", got, []any{1, "test", "bar"})
 	}
 }
 
@@ -183,8 +187,8 @@ func TestAndOrClausesFluent(t *testing.T) {
 		t.Errorf("got %v, want %v", got, want)
 	}
 
-	if got := c.Values(); got[0] != "1" || got[1] != "test" || got[2] != "bar" {
-		t.Errorf("got %v, want %v", got, []string{"1", "test", "bar"})
+	if got := c.Values(); got[0] != 1 || got[1] != "test" || got[2] != "bar" {
+		t.Errorf("got %v, want %v", got, []any{1, "test", "bar"})
 	}
 }
 
@@ -192,42 +196,47 @@ func TestConditions(t *testing.T) {
 	tests := []struct {
 		condition      Clause
 		expectedClause string
-		expectedValues []string
+		expectedValues []any
 	}{
 		{
 			condition:      Equal("id", 1),
 			expectedClause: "(data->>'id' = ?)",
-			expectedValues: []string{"1"},
+			expectedValues: []any{1},
 		},
 		{
 			condition:      GreaterThan("id", 1),
 			expectedClause: "(data->>'id' > ?)",
-			expectedValues: []string{"1"},
+			expectedValues: []any{1},
 		},
 		{
 			condition:      LessThan("id", 1),
 			expectedClause: "(data->>'id' < ?)",
-			expectedValues: []string{"1"},
+			expectedValues: []any{1},
 		},
 		{
 			condition:      LessThanOrEqual("id", 1),
 			expectedClause: "(data->>'id' <= ?)",
-			expectedValues: []string{"1"},
+			expectedValues: []any{1},
 		},
 		{
 			condition:      GreaterThanOrEqual("id", 1),
 			expectedClause: "(data->>'id' >= ?)",
-			expectedValues: []string{"1"},
+			expectedValues: []any{1},
 		},
 		{
 			condition:      NotEqual("id", 1),
 			expectedClause: "(data->>'id' != ?)",
-			expectedValues: []string{"1"},
+			expectedValues: []any{1},
 		},
 		{
 			condition:      Like("id", "%hello%"),
 			expectedClause: "(data->>'id' LIKE ?)",
-			expectedValues: []string{"%hello%"},
+			expectedValues: []any{"%hello%"},
+		},
+		{
+			condition:      NotLike("id", "%hello%"),
+			expectedClause: "(data->>'id' NOT LIKE ?)",
+			expectedValues: []any{"%hello%"},
 		},
 	}
 
@@ -271,3 +280,610 @@ func TestContainsAny(t *testing.T) {
 		t.Errorf("got = %v, want %v", got, expected)
 	}
 }
+
+func TestNotContainsAll(t *testing.T) {
+	c := NotContainsAll("$.list", "one", "two")
+
+	expected := "(NOT ((EXISTS (SELECT 1 FROM json_each(data->>'$.list') WHERE json_each.value = ?)) AND (EXISTS (SELECT 1 FROM json_each(data->>'$.list') WHERE json_each.value = ?))))"
+
+	if got := c.Clause(); got != expected {
+		t.Errorf("got = %v, want %v", got, expected)
+	}
+	if got := c.Values(); len(got) != 2 || got[0] != "one" || got[1] != "two" {
+		t.Errorf("got = %v, want %v", got, []any{"one", "two"})
+	}
+
+	if got, want := NotContainsAll[string]("$.list").Clause(), None().Clause(); got != want {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+}
+
+func TestNotContainsAny(t *testing.T) {
+	c := NotContainsAny("$.list", "one")
+
+	expected := "(NOT (EXISTS (SELECT 1 FROM json_each(data->>'$.list') WHERE json_each.value = ?)))"
+
+	if got := c.Clause(); got != expected {
+		t.Errorf("got = %v, want %v", got, expected)
+	}
+
+	if err := clauseErr(NotContainsAny("$.list'", "one")); !errors.Is(err, ErrInvalidField) {
+		t.Errorf("expected invalid field error got %v", err)
+	}
+
+	if got, want := NotContainsAny[string]("$.list").Clause(), All().Clause(); got != want {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+	if err := clauseErr(NotContainsAny[string]("$.list'")); !errors.Is(err, ErrInvalidField) {
+		t.Errorf("expected invalid field error got %v", err)
+	}
+}
+
+func TestConditionValues(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		condition Clause
+		expected  any
+	}{
+		{Equal("$.count", int64(5)), int64(5)},
+		{Equal("$.count", uint64(5)), uint64(5)},
+		{Equal("$.count", int8(5)), int8(5)},
+		{Equal("$.count", float32(1.5)), float32(1.5)},
+		{Equal("$.name", "five"), "five"},
+		{In("$.at", now), "2024-01-02T03:04:05Z"},
+	}
+
+	for _, test := range tests {
+		if got := test.condition.Values(); got[0] != test.expected {
+			t.Errorf("got = %#v, want %#v", got[0], test.expected)
+		}
+	}
+}
+
+func TestAllClause(t *testing.T) {
+	if got := All().Clause(); got != "TRUE" {
+		t.Errorf("got = %v, want %v", got, "TRUE")
+	}
+
+	c := And(All(), Equal("$.x", 1))
+
+	want := "((data->>'$.x' = ?))"
+	if got := c.Clause(); got != want {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+
+	if got := c.Values(); len(got) != 1 || got[0] != 1 {
+		t.Errorf("got = %v, want %v", got, []any{1})
+	}
+
+	if got := Or(All(), All()).Clause(); got != "TRUE" {
+		t.Errorf("got = %v, want %v", got, "TRUE")
+	}
+
+	c = Or(Equal("$.x", 1), All())
+	if got := c.Clause(); got != "TRUE" {
+		t.Errorf("got = %v, want %v", got, "TRUE")
+	}
+	if got := c.Values(); len(got) != 0 {
+		t.Errorf("got = %v, want %v", got, []any{})
+	}
+
+	c = And(Equal("$.y", 2), Or(All(), Equal("$.x", 1)))
+	want = "((data->>'$.y' = ?))"
+	if got := c.Clause(); got != want {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+
+	if err := clauseErr(Or(All(), Equal("$.x'", 1))); !errors.Is(err, ErrInvalidField) {
+		t.Errorf("expected %v got %v", ErrInvalidField, err)
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"plain", "plain"},
+		{"100%", `100\%`},
+		{"snake_case", `snake\_case`},
+		{`back\slash`, `back\\slash`},
+		{`%_\`, `\%\_\\`},
+	}
+
+	for _, test := range tests {
+		if got := EscapeLike(test.input); got != test.expected {
+			t.Errorf("got = %v, want %v", got, test.expected)
+		}
+	}
+}
+
+func TestStartsWithEndsWith(t *testing.T) {
+	c := StartsWith("$.name", "50%_off")
+
+	want := `(data->>'$.name' LIKE ? ESCAPE '\')`
+	if got := c.Clause(); got != want {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+	if got := c.Values(); got[0] != `50\%\_off%` {
+		t.Errorf("got = %v, want %v", got, []any{`50\%\_off%`})
+	}
+
+	c = EndsWith("$.name", "50%_off")
+	if got := c.Values(); got[0] != `%50\%\_off` {
+		t.Errorf("got = %v, want %v", got, []any{`%50\%\_off`})
+	}
+
+	c = StringContains("$.name", "50%_off")
+	if got := c.Values(); got[0] != `%50\%\_off%` {
+		t.Errorf("got = %v, want %v", got, []any{`%50\%\_off%`})
+	}
+}
+
+func TestValidateFieldPath(t *testing.T) {
+	valid := []string{"$.name", "$.bar.name", "$.list[0]", "$.first_name"}
+	for _, field := range valid {
+		if err := validateFieldPath(field); err != nil {
+			t.Errorf("expected %q to be valid got %v", field, err)
+		}
+	}
+
+	invalid := []string{
+		"$.name' OR 1=1 --",
+		"$.name'",
+		"$.name; DROP TABLE foo",
+		"$.name -- comment",
+		"$.name /* comment */",
+		"$.\"name",
+		"$.`name`",
+	}
+	for _, field := range invalid {
+		if err := validateFieldPath(field); !errors.Is(err, ErrInvalidField) {
+			t.Errorf("expected %q to be invalid got %v", field, err)
+		}
+	}
+}
+
+func TestInvalidFieldClauses(t *testing.T) {
+	field := "$.name' OR 1=1 --"
+
+	clauses := []Clause{
+		Equal(field, "x"),
+		NotEqual(field, 1),
+		LessThan(field, 1),
+		GreaterThan(field, 1),
+		LessThanOrEqual(field, 1),
+		GreaterThanOrEqual(field, 1),
+		Like(field, "%x%"),
+		StartsWith(field, "x"),
+		EndsWith(field, "x"),
+		StringContains(field, "x"),
+		In(field, 1, 2),
+		Between(field, 1, 2),
+		Contains(field, "x"),
+		ContainsAll(field, "x", "y"),
+		ContainsAny(field, "x", "y"),
+		And(Equal("$.id", 1), Equal(field, "x")),
+		Equal("$.id", 1).Or(Equal(field, "x")),
+	}
+
+	for _, c := range clauses {
+		if err := clauseErr(c); !errors.Is(err, ErrInvalidField) {
+			t.Errorf("expected invalid field error got %v", err)
+		}
+		if strings.Contains(c.Clause(), field) {
+			t.Errorf("expected clause not to contain field got %v", c.Clause())
+		}
+	}
+}
+
+func TestContainsWhere(t *testing.T) {
+	c := ContainsWhere("$.addresses", Equal("$.city", "London"))
+
+	expected := "(EXISTS (SELECT 1 FROM (SELECT value AS data FROM (SELECT data->>'$.addresses' AS list), json_each(list)) WHERE (data->>'$.city' = ?)))"
+
+	if got := c.Clause(); got != expected {
+		t.Errorf("got = %v, want %v", got, expected)
+	}
+
+	if got := c.Values(); len(got) != 1 || got[0] != "London" {
+		t.Errorf("got = %v, want %v", got, []any{"London"})
+	}
+
+	c = ContainsWhere("$.addresses", Equal("$.city' --", "London"))
+	if err := clauseErr(c); !errors.Is(err, ErrInvalidField) {
+		t.Errorf("expected invalid field error got %v", err)
+	}
+}
+
+func TestHasKey(t *testing.T) {
+	c := HasKey("$.name")
+
+	expected := "(json_type(data, '$.name') IS NOT NULL)"
+
+	if got := c.Clause(); got != expected {
+		t.Errorf("got = %v, want %v", got, expected)
+	}
+
+	if got := c.Values(); len(got) != 0 {
+		t.Errorf("got = %v, want %v", got, []any{})
+	}
+}
+
+func TestArrayEmpty(t *testing.T) {
+	tests := []struct {
+		condition      Clause
+		expectedClause string
+	}{
+		{ArrayEmpty("$.tags"), "(COALESCE(json_array_length(data, '$.tags'), 0) = 0)"},
+		{ArrayNotEmpty("tags"), "(COALESCE(json_array_length(data, '$.tags'), 0) > 0)"},
+	}
+
+	for _, test := range tests {
+		if got := test.condition.Clause(); got != test.expectedClause {
+			t.Errorf("got = %v, want %v", got, test.expectedClause)
+		}
+
+		if got := test.condition.Values(); len(got) != 0 {
+			t.Errorf("got = %v, want %v", got, []any{})
+		}
+	}
+
+	if err := clauseErr(ArrayEmpty("$.tags'")); !errors.Is(err, ErrInvalidField) {
+		t.Errorf("expected %v got %v", ErrInvalidField, err)
+	}
+}
+
+func TestIsType(t *testing.T) {
+	c := IsType("$.value", JSONInteger)
+
+	expected := "(json_type(data, '$.value') = ?)"
+
+	if got := c.Clause(); got != expected {
+		t.Errorf("got = %v, want %v", got, expected)
+	}
+
+	if got := c.Values(); got[0] != "integer" {
+		t.Errorf("got = %v, want %v", got, []any{"integer"})
+	}
+}
+
+func TestClausePrecedence(t *testing.T) {
+	a := Equal("$.a", 1)
+	b := Equal("$.b", 2)
+	c := Equal("$.c", 3)
+
+	tests := []struct {
+		clause   Clause
+		expected string
+	}{
+		{a.And(b).Or(c), "(((data->>'$.a' = ?) AND (data->>'$.b' = ?)) OR (data->>'$.c' = ?))"},
+		{a.And(b.Or(c)), "((data->>'$.a' = ?) AND ((data->>'$.b' = ?) OR (data->>'$.c' = ?)))"},
+		{a.Or(b).And(c), "(((data->>'$.a' = ?) OR (data->>'$.b' = ?)) AND (data->>'$.c' = ?))"},
+		{a.Or(b.And(c)), "((data->>'$.a' = ?) OR ((data->>'$.b' = ?) AND (data->>'$.c' = ?)))"},
+		{Group(a.And(b)).Or(c), "((((data->>'$.a' = ?) AND (data->>'$.b' = ?))) OR (data->>'$.c' = ?))"},
+		{Group(a), "((data->>'$.a' = ?))"},
+	}
+
+	for _, test := range tests {
+		if got := test.clause.Clause(); got != test.expected {
+			t.Errorf("got = %v, want %v", got, test.expected)
+		}
+
+		if got := test.clause.Values(); len(got) > 1 && (got[0] != 1 || got[1] != 2 || got[2] != 3) {
+			t.Errorf("got = %v, want %v", got, []any{1, 2, 3})
+		}
+	}
+}
+
+func TestJSONField(t *testing.T) {
+	tests := []struct {
+		field    string
+		expected string
+	}{
+		{"id", "data->>'id'"},
+		{"$.id", "data->>'$.id'"},
+		{"$.a.b.c", "data->>'$.a.b.c'"},
+		{"a.b.c", "data->>'$.a.b.c'"},
+		{"$.a[0].b", "data->>'$.a[0].b'"},
+		{"$.list[0]", "data->>'$.list[0]'"},
+		{"list[0]", "data->>'$.list[0]'"},
+		{"list[#-1]", "data->>'$.list[#-1]'"},
+	}
+
+	for _, test := range tests {
+		if got := jsonField(test.field); got != test.expected {
+			t.Errorf("got = %v, want %v", got, test.expected)
+		}
+	}
+
+	if got := HasKey("a.b").Clause(); got != "(json_type(data, '$.a.b') IS NOT NULL)" {
+		t.Errorf("got = %v, want %v", got, "(json_type(data, '$.a.b') IS NOT NULL)")
+	}
+}
+
+func TestAnyOf(t *testing.T) {
+	c := AnyOf("$.name", "a", "b", "c")
+	want := Or(Equal("$.name", "a"), Equal("$.name", "b"), Equal("$.name", "c"))
+
+	if got := c.Clause(); got != want.Clause() {
+		t.Errorf("got = %v, want %v", got, want.Clause())
+	}
+
+	if got := c.Values(); len(got) != 3 || got[0] != "a" || got[1] != "b" || got[2] != "c" {
+		t.Errorf("got = %v, want %v", got, want.Values())
+	}
+
+	n := AnyOf("$.id", 1, 2)
+	if got := n.Values(); got[0] != 1 || got[1] != 2 {
+		t.Errorf("got = %v, want %v", got, []any{1, 2})
+	}
+
+	if got := AnyOf[string]("$.name").Clause(); got != "FALSE" {
+		t.Errorf("got = %v, want %v", got, "FALSE")
+	}
+
+	if err := clauseErr(AnyOf[string]("$.name'")); !errors.Is(err, ErrInvalidField) {
+		t.Errorf("expected invalid field error got %v", err)
+	}
+}
+
+func TestNumericConditions(t *testing.T) {
+	tests := []struct {
+		condition      Clause
+		expectedClause string
+	}{
+		{NumericEqual("$.n", 1), "(CAST(data->>'$.n' AS REAL) = ?)"},
+		{NumericLessThan("$.n", 1), "(CAST(data->>'$.n' AS REAL) < ?)"},
+		{NumericGreaterThan("$.n", 1), "(CAST(data->>'$.n' AS REAL) > ?)"},
+		{NumericLessThanOrEqual("$.n", 1), "(CAST(data->>'$.n' AS REAL) <= ?)"},
+		{NumericGreaterThanOrEqual("$.n", 1), "(CAST(data->>'$.n' AS REAL) >= ?)"},
+	}
+
+	for _, test := range tests {
+		if got := test.condition.Clause(); got != test.expectedClause {
+			t.Errorf("got = %v, want %v", got, test.expectedClause)
+		}
+
+		if got := test.condition.Values(); got[0] != 1 {
+			t.Errorf("got = %v, want %v", got, []any{1})
+		}
+	}
+}
+
+func TestTimeConditions(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 12, 30, 0, 500000000, time.FixedZone("", 3600))
+
+	tests := []struct {
+		condition      Clause
+		expectedClause string
+	}{
+		{After("$.at", ts), "(unixepoch(data->>'$.at', 'subsec') > unixepoch(?, 'subsec'))"},
+		{Before("$.at", ts), "(unixepoch(data->>'$.at', 'subsec') < unixepoch(?, 'subsec'))"},
+	}
+
+	for _, test := range tests {
+		if got := test.condition.Clause(); got != test.expectedClause {
+			t.Errorf("got = %v, want %v", got, test.expectedClause)
+		}
+
+		if got := test.condition.Values(); got[0] != "2024-03-01T11:30:00.5Z" {
+			t.Errorf("got = %v, want %v", got, []any{"2024-03-01T11:30:00.5Z"})
+		}
+	}
+}
+
+func TestBetweenTime(t *testing.T) {
+	from := time.Date(2024, 3, 1, 12, 30, 0, 500000000, time.FixedZone("", 3600))
+	to := from.Add(time.Hour)
+
+	c := BetweenTime("$.at", from, to)
+
+	expectedClause := "(unixepoch(data->>'$.at', 'subsec') BETWEEN unixepoch(?, 'subsec') AND unixepoch(?, 'subsec'))"
+	if got := c.Clause(); got != expectedClause {
+		t.Errorf("got = %v, want %v", got, expectedClause)
+	}
+
+	want := []any{"2024-03-01T11:30:00.5Z", "2024-03-01T12:30:00.5Z"}
+	if got := c.Values(); !slices.Equal(got, want) {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+
+	if err := clauseErr(BetweenTime("$.at'", from, to)); !errors.Is(err, ErrInvalidField) {
+		t.Errorf("expected %v got %v", ErrInvalidField, err)
+	}
+}
+
+func TestCompositeKey(t *testing.T) {
+	c := CompositeKey([]string{"$.tenant", "$.id"}, "a", 1)
+
+	want := "((data->>'$.tenant' IN (?)) AND (data->>'$.id' IN (?)))"
+	if got := c.Clause(); got != want {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+	if got := c.Values(); len(got) != 2 || got[0] != "a" || got[1] != 1 {
+		t.Errorf("got = %v, want %v", got, []any{"a", 1})
+	}
+
+	if err := clauseErr(CompositeKey([]string{"$.tenant", "$.id"}, "a")); err == nil {
+		t.Error("expected error for mismatched values got nil")
+	}
+	if err := clauseErr(CompositeKey([]string{"$.tenant'"}, "a")); !errors.Is(err, ErrInvalidField) {
+		t.Errorf("expected invalid field error got %v", err)
+	}
+}
+
+func TestElementConditions(t *testing.T) {
+	c := AnyElement("$.scores", ">", 90)
+	want := "(EXISTS (SELECT 1 FROM json_each(data->>'$.scores') WHERE json_each.value > ?))"
+	if got := c.Clause(); got != want {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+	if got := c.Values(); got[0] != int64(90) {
+		t.Errorf("got = %v, want %v", got, []any{int64(90)})
+	}
+
+	// numbers are bound as Contains binds them, matching how they are stored in JSON
+	if got := AnyElement("$.scores", "=", float32(0.1)).Values(); got[0] != 0.1 {
+		t.Errorf("got = %v, want %v", got, []any{0.1})
+	}
+	if got := AllElements("$.scores", "<", uint8(7)).Values(); got[0] != int64(7) {
+		t.Errorf("got = %v, want %v", got, []any{int64(7)})
+	}
+
+	c = AllElements("$.scores", ">=", 50)
+	want = "(data->>'$.scores' IS NOT NULL AND NOT EXISTS (SELECT 1 FROM json_each(data->>'$.scores') WHERE NOT (json_each.value >= ?)))"
+	if got := c.Clause(); got != want {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+
+	op := "<"
+	c = AnyElement("$.scores", op, 10)
+	want = "(EXISTS (SELECT 1 FROM json_each(data->>'$.scores') WHERE json_each.value < ?))"
+	if got := c.Clause(); got != want {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+
+	if err := clauseErr(AnyElement("$.scores", "> 0 OR 1 =", 90)); err == nil {
+		t.Error("expected error for unsupported operator got nil")
+	}
+	if err := clauseErr(AllElements("$.scores'", ">", 90)); !errors.Is(err, ErrInvalidField) {
+		t.Errorf("expected invalid field error got %v", err)
+	}
+}
+
+func TestIs(t *testing.T) {
+	c := Is("$.name", "x")
+
+	want := "(data->>'$.name' IS ?)"
+	if got := c.Clause(); got != want {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+	if got := c.Values(); got[0] != "x" {
+		t.Errorf("got = %v, want %v", got, []any{"x"})
+	}
+
+	if got := Is("$.name", nil).Values(); got[0] != nil {
+		t.Errorf("got = %v, want %v", got, []any{nil})
+	}
+}
+
+func TestUnixTimeConditions(t *testing.T) {
+	ts := time.UnixMilli(1709296200500)
+
+	c := AfterUnix("$.ts", ts)
+	if got := c.Clause(); got != "(data->>'$.ts' > ?)" {
+		t.Errorf("got = %v, want %v", got, "(data->>'$.ts' > ?)")
+	}
+	if got := c.Values(); got[0] != int64(1709296200500) {
+		t.Errorf("got = %v, want %v", got, []any{int64(1709296200500)})
+	}
+
+	c = BeforeUnix("$.ts", ts)
+	if got := c.Clause(); got != "(data->>'$.ts' < ?)" {
+		t.Errorf("got = %v, want %v", got, "(data->>'$.ts' < ?)")
+	}
+}
+
+func TestFields(t *testing.T) {
+	c := And(
+		Equal("$.name", "x"),
+		Or(GreaterThan("$.age", 18), Contains("$.tags", "a")),
+		Or(Equal("$.name", "y"), ContainsWhere("$.addresses", Equal("$.city", "London"))),
+		All(),
+	)
+
+	want := []string{"$.name", "$.age", "$.tags", "$.addresses"}
+	if got := Fields(c); !slices.Equal(got, want) {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+
+	if got := Fields(None()); len(got) != 0 {
+		t.Errorf("got = %v, want %v", got, []string{})
+	}
+}
+
+func TestLikeEscape(t *testing.T) {
+	c := LikeEscape("$.discount", "50!%", '!')
+
+	want := "(data->>'$.discount' LIKE ? ESCAPE ?)"
+	if got := c.Clause(); got != want {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+	if got := c.Values(); !slices.Equal(got, []any{"50!%", "!"}) {
+		t.Errorf("got = %v, want %v", got, []any{"50!%", "!"})
+	}
+}
+
+func TestRaw(t *testing.T) {
+	c := Raw("length(data->>'name') > ?", 3)
+
+	want := "(length(data->>'name') > ?)"
+	if got := c.Clause(); got != want {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+	if got := c.Values(); !slices.Equal(got, []any{3}) {
+		t.Errorf("got = %v, want %v", got, []any{3})
+	}
+}
+
+func TestBytesEqual(t *testing.T) {
+	c := BytesEqual("$.bytes", []byte{0x00, 0xff, 0x10})
+
+	want := "(data->>'$.bytes' = ?)"
+	if got := c.Clause(); got != want {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+	if got := c.Values(); !slices.Equal(got, []any{"AP8Q"}) {
+		t.Errorf("got = %v, want %v", got, []any{"AP8Q"})
+	}
+}
+
+func TestJSONEqual(t *testing.T) {
+	c := JSONEqual("bar", Bar{Name: "x"})
+
+	want := "(json_extract(data, '$.bar') = json_extract(?, '$'))"
+	if got := c.Clause(); got != want {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+	if got := c.Values(); !slices.Equal(got, []any{`{"name":"x"}`}) {
+		t.Errorf("got = %v, want %v", got, []any{`{"name":"x"}`})
+	}
+
+	if err := clauseErr(JSONEqual("bar", make(chan int))); err == nil {
+		t.Error("expected error for unencodable value got nil")
+	}
+}
+
+type testStatus int
+
+const (
+	testStatusInactive testStatus = iota
+	testStatusActive
+)
+
+func (s testStatus) String() string {
+	return [...]string{"inactive", "active"}[s]
+}
+
+type testName string
+
+func TestNamedTypeValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		clause Clause
+		want   []any
+	}{
+		{"enum", Equal("$.status", testStatusActive), []any{int64(1)}},
+		{"enum in", In("$.status", testStatusInactive, testStatusActive), []any{int64(0), int64(1)}},
+		{"named string", In("$.name", testName("x")), []any{"x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.clause.Values(); !slices.Equal(got, tt.want) {
+				t.Errorf("got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package nosqlite
+
+import (
+	"encoding/json"
+)
+
+// Codec marshals and unmarshals the documents stored in a table
+type Codec interface {
+	// Marshal encodes v into the bytes stored in the data column
+	Marshal(v any) ([]byte, error)
+	// Unmarshal decodes the bytes read from the data column into v
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec is the default Codec and uses encoding/json
+type JSONCodec struct{}
+
+// Marshal encodes v using json.Marshal
+func (JSONCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal decodes data using json.Unmarshal
+func (JSONCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,81 @@
+package nosqlite
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// upperNameCodec uppercases Foo.Name on the way into the store
+type upperNameCodec struct {
+	JSONCodec
+}
+
+func (c upperNameCodec) Marshal(v any) ([]byte, error) {
+	if f, ok := v.(Foo); ok {
+		f.Name = strings.ToUpper(f.Name)
+		v = f
+	}
+	return c.JSONCodec.Marshal(v)
+}
+
+func TestStore_WithCodec(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := NewStore(helperTempFile(t), WithCodec(upperNameCodec{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	err = table.Insert(ctx, Foo{Name: "codec"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	val, err := table.QueryOne(ctx, Equal("$.name", "CODEC"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val == nil {
+		t.Fatal("expected result got nil")
+	}
+	if val.Name != "CODEC" {
+		t.Errorf("expected CODEC got %s", val.Name)
+	}
+}
+
+func TestTable_WithTableCodec(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table, err := NewTable[Foo](ctx, store, WithTableCodec(upperNameCodec{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = table.Insert(ctx, Foo{Name: "codec"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	val, err := table.QueryOne(ctx, Equal("$.name", "codec"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val != nil {
+		t.Fatal("expected nil result")
+	}
+
+	val, err = table.QueryOne(ctx, Equal("$.name", "CODEC"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val == nil || val.Name != "CODEC" {
+		t.Errorf("expected CODEC got %v", val)
+	}
+}
@@ -0,0 +1,76 @@
+package nosqlite
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Debug renders c with its values substituted for its parameters, for logging and
+// pasting into a SQLite shell when debugging.
+//
+// Debug is NOT safe for building statements to execute, always execute clauses
+// using their parameters.
+func Debug(c Clause) string {
+	s, _ := interpolate(c, func(v any) (string, error) {
+		return debugLiteral(v), nil
+	})
+	return s
+}
+
+// interpolate substitutes each parameter of c, outside of quoted strings, with the
+// literal rendering of its value
+func interpolate(c Clause, literal func(v any) (string, error)) (string, error) {
+	clause := c.Clause()
+	values := c.Values()
+
+	var b strings.Builder
+	inQuote := false
+	next := 0
+	for _, r := range clause {
+		switch {
+		case r == '\'':
+			inQuote = !inQuote
+		case r == '?' && !inQuote && next < len(values):
+			lit, err := literal(values[next])
+			if err != nil {
+				return "", err
+			}
+			b.WriteString(lit)
+			next++
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String(), nil
+}
+
+// sqlLiteral renders v as a SQL literal, returning an error for values without
+// an unambiguous literal form
+func sqlLiteral(v any) (string, error) {
+	switch t := v.(type) {
+	case nil:
+		return "NULL", nil
+	case string:
+		return "'" + strings.ReplaceAll(t, "'", "''") + "'", nil
+	case []byte:
+		return fmt.Sprintf("X'%X'", t), nil
+	case bool:
+		if t {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprintf("%v", t), nil
+	default:
+		return "", fmt.Errorf("cannot render %T as a literal", v)
+	}
+}
+
+// debugLiteral renders v as a SQL literal, quoting its string form if it has no literal form
+func debugLiteral(v any) string {
+	lit, err := sqlLiteral(v)
+	if err != nil {
+		lit, _ = sqlLiteral(fmt.Sprintf("%v", v))
+	}
+	return lit
+}
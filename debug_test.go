@@ -0,0 +1,29 @@
+package nosqlite
+
+import "testing"
+
+func TestDebug(t *testing.T) {
+	tests := []struct {
+		clause   Clause
+		expected string
+	}{
+		{
+			clause:   And(Equal("$.id", 1), Equal("$.name", "o'brien")),
+			expected: "((data->>'$.id' = 1) AND (data->>'$.name' = 'o''brien'))",
+		},
+		{
+			clause:   In("$.tag?", "a", []byte{0xca, 0xfe}, true),
+			expected: "(data->>'$.tag?' IN ('a',X'CAFE',TRUE))",
+		},
+		{
+			clause:   All(),
+			expected: "TRUE",
+		},
+	}
+
+	for _, test := range tests {
+		if got := Debug(test.clause); got != test.expected {
+			t.Errorf("got = %v, want %v", got, test.expected)
+		}
+	}
+}
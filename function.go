@@ -0,0 +1,169 @@
+package nosqlite
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+
+	sqlite "github.com/glebarez/go-sqlite"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// RegisterFunc registers impl as a scalar SQL function called name, which can then be called
+// from Raw clauses. impl must be a function, optionally variadic, taking string, []byte, bool,
+// integer, float or any arguments and returning a single value of one of those types,
+// optionally followed by an error. SQL NULL arguments are passed as the zero value.
+//
+// Functions are registered with the sqlite driver so are shared by every store in the process,
+// and registering the same name twice returns an error. Connections only see functions
+// registered before they were opened, so RegisterFunc closes the store's idle connections and
+// should be called before the store is used concurrently. Closing the connection would also
+// detach any databases attached with Attach, so RegisterFunc returns an error while there are.
+func (s *Store) RegisterFunc(name string, impl any) error {
+	s.attachMu.Lock()
+	defer s.attachMu.Unlock()
+
+	if len(s.attached) > 0 {
+		return fmt.Errorf("function %q: cannot register while databases are attached", name)
+	}
+
+	nArg, fn, err := scalarFunction(impl)
+	if err != nil {
+		return fmt.Errorf("function %q: %w", name, err)
+	}
+
+	err = sqlite.RegisterScalarFunction(name, nArg, fn)
+	if err != nil {
+		return err
+	}
+
+	return s.closeIdleConns()
+}
+
+// closeIdleConns closes the store's idle connections, leaving its idle connection limit as the
+// caller configured it
+func (s *Store) closeIdleConns() error {
+	for i := s.db.Stats().Idle; i > 0; i-- {
+		conn, err := s.db.Conn(context.Background())
+		if err != nil {
+			return err
+		}
+		// database/sql discards a connection rather than reusing it when it reports ErrBadConn
+		_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+		_ = conn.Close()
+	}
+	return nil
+}
+
+// scalarFunction adapts impl to the signature expected by the driver, returning the number
+// of arguments it takes, or -1 if it is variadic
+func scalarFunction(impl any) (int32, func(*sqlite.FunctionContext, []driver.Value) (driver.Value, error), error) {
+	v := reflect.ValueOf(impl)
+	t := v.Type()
+	if t.Kind() != reflect.Func {
+		return 0, nil, fmt.Errorf("expected a function got %T", impl)
+	}
+	if t.NumOut() == 0 || t.NumOut() > 2 || (t.NumOut() == 2 && t.Out(1) != errorType) {
+		return 0, nil, fmt.Errorf("expected a function returning a value and optionally an error got %s", t)
+	}
+
+	nArg := int32(t.NumIn())
+	if t.IsVariadic() {
+		nArg = -1
+	}
+
+	fn := func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		if t.IsVariadic() && len(args) < t.NumIn()-1 {
+			return nil, fmt.Errorf("expected at least %d arguments got %d", t.NumIn()-1, len(args))
+		}
+
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			argType := t.In(min(i, t.NumIn()-1))
+			if t.IsVariadic() && i >= t.NumIn()-1 {
+				argType = argType.Elem()
+			}
+
+			var err error
+			in[i], err = functionArg(arg, argType)
+			if err != nil {
+				return nil, fmt.Errorf("argument %d: %w", i, err)
+			}
+		}
+
+		out := v.Call(in)
+		if len(out) == 2 && !out[1].IsNil() {
+			return nil, out[1].Interface().(error)
+		}
+		return functionResult(out[0])
+	}
+
+	return nArg, fn, nil
+}
+
+// functionArg converts arg, as passed by the driver, to a value of type t
+func functionArg(arg driver.Value, t reflect.Type) (reflect.Value, error) {
+	if arg == nil {
+		return reflect.Zero(t), nil
+	}
+	if t.Kind() == reflect.Interface {
+		if !reflect.TypeOf(arg).Implements(t) {
+			return reflect.Value{}, fmt.Errorf("cannot use %T as %s", arg, t)
+		}
+		v := reflect.New(t).Elem()
+		v.Set(reflect.ValueOf(arg))
+		return v, nil
+	}
+
+	v := reflect.ValueOf(arg)
+	switch arg.(type) {
+	case int64:
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			return v.Convert(t), nil
+		case reflect.Bool:
+			return reflect.ValueOf(v.Int() != 0).Convert(t), nil
+		}
+	case float64:
+		switch t.Kind() {
+		case reflect.Float32, reflect.Float64:
+			return v.Convert(t), nil
+		}
+	case string, []byte:
+		if t.Kind() == reflect.String || (t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8) {
+			return v.Convert(t), nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("cannot use %T as %s", arg, t)
+}
+
+// functionResult converts v, as returned by a registered function, to a value the driver
+// can return to SQLite
+func functionResult(v reflect.Value) (driver.Value, error) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Bytes(), nil
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return functionResult(v.Elem())
+	}
+	return nil, fmt.Errorf("unsupported result type %s", v.Type())
+}
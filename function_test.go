@@ -0,0 +1,210 @@
+package nosqlite
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// registered tracks the functions registered by tests, as the driver only allows each name
+// to be registered once per process, e.g. when running with -count
+var registered sync.Map
+
+// registeredConnections numbers the functions registered by TestStore_RegisterFuncConnections
+var registeredConnections atomic.Int64
+
+func helperRegisterFunc(t *testing.T, store *Store, name string, impl any) {
+	t.Helper()
+
+	if _, ok := registered.LoadOrStore(name, struct{}{}); ok {
+		return
+	}
+	err := store.RegisterFunc(name, impl)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStore_RegisterFunc(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	helperRegisterFunc(t, store, "nosqlite_test_concat", func(parts ...string) string {
+		return strings.Join(parts, "")
+	})
+
+	for _, foo := range []Foo{{Name: "a", Bar: Bar{Name: "b"}}, {Name: "ab"}} {
+		err := table.Insert(ctx, foo)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vals, err := table.QueryMany(ctx, Raw("nosqlite_test_concat(data->>'name', data->>'$.bar.name') = ?", "ab"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 2 {
+		t.Errorf("expected 2 got %v", vals)
+	}
+
+	vals, err = table.QueryMany(ctx, Raw("nosqlite_test_concat(data->>'name', '-', data->>'$.bar.name') = ?", "a-b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 1 || vals[0].Name != "a" {
+		t.Errorf("expected [a] got %v", vals)
+	}
+}
+
+func TestStore_RegisterFuncConnections(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	store.db.SetMaxIdleConns(4)
+
+	conns := make([]*sql.Conn, 4)
+	for i := range conns {
+		conn, err := store.db.Conn(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns[i] = conn
+	}
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+
+	// registered under a new name on each run as this test needs RegisterFunc to succeed
+	name := fmt.Sprintf("nosqlite_test_connections_%d", registeredConnections.Add(1))
+	err := store.RegisterFunc(name, func() int { return 1 })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if idle := store.db.Stats().Idle; idle != 0 {
+		t.Errorf("expected idle connections to be closed got %d", idle)
+	}
+
+	// the idle connection limit set on the database is kept
+	for i := range conns {
+		conn, err := store.db.Conn(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns[i] = conn
+	}
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
+	if idle := store.db.Stats().Idle; idle != 4 {
+		t.Errorf("expected 4 idle connections got %d", idle)
+	}
+
+	var got int
+	err = store.QueryRowContext(ctx, fmt.Sprintf("SELECT %s()", name)).Scan(&got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 1 {
+		t.Errorf("expected 1 got %d", got)
+	}
+}
+
+func TestStore_RegisterFuncAttached(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	err := store.Attach(ctx, helperTempFile(t), "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attached, err := NewTable[Document](ctx, store, WithSchema("other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.RegisterFunc("nosqlite_test_attached", func() int { return 1 })
+	if err == nil {
+		t.Error("expected error registering while a database is attached got nil")
+	}
+
+	_, err = attached.Count(ctx)
+	if err != nil {
+		t.Errorf("expected attached table to remain usable got %v", err)
+	}
+}
+
+func TestStore_RegisterFuncError(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	err := store.RegisterFunc("nosqlite_test_invalid", "not a function")
+	if err == nil {
+		t.Error("expected error registering a non-function got nil")
+	}
+
+	helperRegisterFunc(t, store, "nosqlite_test_failing", func(s string) (int, error) {
+		return 0, errors.New("failed")
+	})
+
+	err = table.Insert(ctx, Foo{Name: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = table.QueryMany(ctx, Raw("nosqlite_test_failing(data->>'name') = 0"))
+	if err == nil || !strings.Contains(err.Error(), "failed") {
+		t.Errorf("expected failed got %v", err)
+	}
+}
+
+func TestScalarFunction(t *testing.T) {
+	nArg, fn, err := scalarFunction(func(n int, f float64, ok bool, s string, b []byte, v any) (string, error) {
+		return strings.Join([]string{
+			strings.Repeat("n", n), strings.Repeat("f", int(f)), map[bool]string{true: "t", false: "f"}[ok], s, string(b), v.(string),
+		}, ","), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nArg != 6 {
+		t.Errorf("expected 6 got %d", nArg)
+	}
+
+	got, err := fn(nil, []driver.Value{int64(2), float64(3), int64(1), "s", []byte("b"), "v"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "nn,fff,t,s,b,v" {
+		t.Errorf("expected nn,fff,t,s,b,v got %v", got)
+	}
+
+	_, err = fn(nil, []driver.Value{"x", float64(3), int64(1), "s", []byte("b"), "v"})
+	if err == nil {
+		t.Error("expected error converting string to int got nil")
+	}
+
+	_, _, err = scalarFunction(func() {})
+	if err == nil {
+		t.Error("expected error for function without result got nil")
+	}
+}
@@ -0,0 +1,138 @@
+package nosqlite
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxJSONLLineSize is the largest document ImportJSONL will read from a single line
+const maxJSONLLineSize = 16 * 1024 * 1024
+
+// ExportOption configures ExportJSONL
+type ExportOption func(*exportOptions)
+
+type exportOptions struct {
+	sortedKeys bool
+}
+
+// WithSortedKeys writes each document with the keys of every object in sorted order, and
+// the documents in insertion order, so that exporting the same items always produces the
+// same bytes, e.g. for snapshot tests or diffs
+func WithSortedKeys() ExportOption {
+	return func(o *exportOptions) {
+		o.sortedKeys = true
+	}
+}
+
+// sortKeys re-encodes the JSON document data with the keys of every object sorted
+func sortKeys(data string) (string, error) {
+	decoder := json.NewDecoder(strings.NewReader(data))
+	// keep numbers as written rather than converting them to float64
+	decoder.UseNumber()
+
+	var v any
+	if err := decoder.Decode(&v); err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// ExportJSONL writes the stored document of every item matching clause to w,
+// one per line, returning the number of items written. The expiry recorded by a
+// view from WithTTL is left out so that importing the documents does not restore it.
+func (n *Table[T]) ExportJSONL(ctx context.Context, w io.Writer, clause Clause, opts ...ExportOption) (int64, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	options := &exportOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	clause = n.scoped(clause)
+	if err := clauseErr(clause); err != nil {
+		return 0, err
+	}
+
+	var data string
+	var count int64
+
+	queryStatement := fmt.Sprintf("%s json_remove(data, '%s') FROM %s WHERE %s", "SELECT", expiresAtField, n.tableRef(), clause.Clause())
+	if options.sortedKeys {
+		queryStatement += " ORDER BY rowid"
+	}
+	rows, err := n.store.queryContext(ctx, queryStatement, clause.Values()...)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		err = rows.Scan(&data)
+		if err != nil {
+			return count, err
+		}
+
+		if options.sortedKeys {
+			data, err = sortKeys(data)
+			if err != nil {
+				return count, err
+			}
+		}
+
+		_, err = io.WriteString(w, data+"\n")
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, rows.Err()
+}
+
+// ImportJSONL inserts a document for every non-empty line read from r within a single
+// transaction, returning the number of items inserted. If any line fails to decode
+// into T nothing is inserted.
+func (n *Table[T]) ImportJSONL(ctx context.Context, r io.Reader) (int64, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	var count int64
+
+	err := n.store.WithTx(ctx, func(tx *Transaction) error {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxJSONLLineSize)
+
+		line := 0
+		for scanner.Scan() {
+			line++
+
+			b := bytes.TrimSpace(scanner.Bytes())
+			if len(b) == 0 {
+				continue
+			}
+
+			var data T
+			err := n.codec.Unmarshal(b, &data)
+			if err != nil {
+				return fmt.Errorf("failed to decode line %d: %w", line, err)
+			}
+
+			err = n.insert(ctx, tx, data)
+			if err != nil {
+				return fmt.Errorf("failed to insert line %d: %w", line, err)
+			}
+			count++
+		}
+		return scanner.Err()
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
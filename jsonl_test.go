@@ -0,0 +1,194 @@
+package nosqlite
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTable_ExportJSONL(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	foos := []Foo{
+		{Id: 1, Name: "export-one"},
+		{Id: 2, Name: "export-two"},
+		{Id: 3, Name: "skip"},
+	}
+
+	for _, f := range foos {
+		err := table.Insert(ctx, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	count, err := table.ExportJSONL(ctx, &buf, Like("$.name", "export-%"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 got %d", count)
+	}
+
+	var exported []Foo
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var f Foo
+		err := json.Unmarshal(scanner.Bytes(), &f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exported = append(exported, f)
+	}
+
+	if len(exported) != 2 || exported[0].Name != "export-one" || exported[1].Name != "export-two" {
+		t.Errorf("expected export-one and export-two got %v", exported)
+	}
+}
+
+func TestTable_ExportJSONLWithTTL(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Document](ctx, t, store)
+
+	err := table.WithTTL(time.Hour).Insert(ctx, Document{"id": "cached"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	_, err = table.ExportJSONL(ctx, &buf, All())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"id":"cached"}` + "\n"
+	if got := buf.String(); got != expected {
+		t.Errorf("got = %v, want %v", got, expected)
+	}
+}
+
+type Unsorted struct {
+	Zebra  string         `json:"zebra"`
+	Apple  int64          `json:"apple"`
+	Nested map[string]any `json:"nested"`
+}
+
+func TestTable_ExportJSONLSortedKeys(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Unsorted](ctx, t, store)
+
+	items := []Unsorted{
+		{Zebra: "one", Apple: 9007199254740993, Nested: map[string]any{"b": 1, "a": []any{map[string]any{"d": 1, "c": 2}}}},
+		{Zebra: "two", Apple: 2},
+	}
+	for _, item := range items {
+		err := table.Insert(ctx, item)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	export := func() string {
+		var buf bytes.Buffer
+		_, err := table.ExportJSONL(ctx, &buf, All(), WithSortedKeys())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	first := export()
+	if second := export(); first != second {
+		t.Errorf("expected identical exports got %q and %q", first, second)
+	}
+
+	want := `{"apple":9007199254740993,"nested":{"a":[{"c":2,"d":1}],"b":1},"zebra":"one"}` + "\n" +
+		`{"apple":2,"nested":null,"zebra":"two"}` + "\n"
+	if first != want {
+		t.Errorf("got = %v, want %v", first, want)
+	}
+}
+
+func TestTable_ImportJSONL(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	source := helperTable[IDOne](ctx, t, store)
+	target := helperTable[IDTwo](ctx, t, store)
+
+	for _, id := range []string{"one", "two", "three"} {
+		err := source.Insert(ctx, IDOne{ID: id})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	_, err := source.ExportJSONL(ctx, &buf, All())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := target.ImportJSONL(ctx, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 got %d", count)
+	}
+
+	val, err := target.QueryOne(ctx, Equal("$.id", "two"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val == nil {
+		t.Fatal("expected result got nil")
+	}
+}
+
+func TestTable_ImportJSONLMalformed(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	input := bytes.NewBufferString("{\"id\":1}\n\n{\"id\":2\n{\"id\":3}\n")
+
+	_, err := table.ImportJSONL(ctx, input)
+	if err == nil {
+		t.Fatal("expected error got nil")
+	}
+	if !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("expected error to mention line 3 got %v", err)
+	}
+
+	count, err := table.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("expected 0 got %d", count)
+	}
+}
@@ -0,0 +1,89 @@
+package nosqlite
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type direction string
+
+var (
+	ascending  direction = "ASC"
+	descending direction = "DESC"
+)
+
+type nullsOrder string
+
+var (
+	nullsFirst nullsOrder = "NULLS FIRST"
+	nullsLast  nullsOrder = "NULLS LAST"
+)
+
+// Order describes how to order the results of a query on a field
+// By default null and missing values sort before other values when ascending
+// and after them when descending
+type Order struct {
+	Field     string
+	Direction direction
+	Nulls     nullsOrder
+}
+
+// rowidOrder orders items by insertion, an Order with an empty Field sorts on rowid
+var rowidOrder = Order{Field: "", Direction: ascending}
+
+// Asc returns an Order that sorts a field in ascending order
+func Asc(field string) Order {
+	return Order{Field: field, Direction: ascending}
+}
+
+// Desc returns an Order that sorts a field in descending order
+func Desc(field string) Order {
+	return Order{Field: field, Direction: descending}
+}
+
+// NullsFirst returns a copy of the order that sorts null and missing values first
+func (o Order) NullsFirst() Order {
+	o.Nulls = nullsFirst
+	return o
+}
+
+// NullsLast returns a copy of the order that sorts null and missing values last
+func (o Order) NullsLast() Order {
+	o.Nulls = nullsLast
+	return o
+}
+
+func (o Order) term() string {
+	if o.Field == "" {
+		return fmt.Sprintf("rowid %s", o.Direction)
+	}
+	if o.Nulls != "" {
+		return fmt.Sprintf("%s %s %s", jsonField(o.Field), o.Direction, o.Nulls)
+	}
+	return fmt.Sprintf("%s %s", jsonField(o.Field), o.Direction)
+}
+
+// validateOrders returns an error if any order has an invalid field path
+func validateOrders(orders []Order) error {
+	var errs []error
+	for _, o := range orders {
+		if o.Field != "" {
+			errs = append(errs, validateFieldPath(o.Field))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// orderByClause returns the ORDER BY clause for orders, or an empty string if there are none
+func orderByClause(orders ...Order) string {
+	if len(orders) == 0 {
+		return ""
+	}
+
+	terms := make([]string, len(orders))
+	for i, o := range orders {
+		terms[i] = o.term()
+	}
+	return fmt.Sprintf("ORDER BY %s", strings.Join(terms, ", "))
+}
@@ -0,0 +1,23 @@
+package nosqlite
+
+import "testing"
+
+func TestOrderByClause(t *testing.T) {
+	tests := []struct {
+		orders   []Order
+		expected string
+	}{
+		{nil, ""},
+		{[]Order{Asc("$.name")}, "ORDER BY data->>'$.name' ASC"},
+		{[]Order{Desc("$.id"), Asc("$.name")}, "ORDER BY data->>'$.id' DESC, data->>'$.name' ASC"},
+		{[]Order{Desc("$.id"), rowidOrder}, "ORDER BY data->>'$.id' DESC, rowid ASC"},
+		{[]Order{Asc("$.ts").NullsLast()}, "ORDER BY data->>'$.ts' ASC NULLS LAST"},
+		{[]Order{Desc("$.ts").NullsFirst(), Asc("$.id")}, "ORDER BY data->>'$.ts' DESC NULLS FIRST, data->>'$.id' ASC"},
+	}
+
+	for _, test := range tests {
+		if got := orderByClause(test.orders...); got != test.expected {
+			t.Errorf("got = %v, want %v", got, test.expected)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package nosqlite
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+)
+
+const defaultStatementCacheSize = 32
+
+// stmtCache is an LRU of prepared statements keyed by their SQL
+type stmtCache struct {
+	mu    sync.Mutex
+	db    *sql.DB
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+// cachedStmt tracks in-flight users so an evicted statement is only closed
+// once the last user has released it
+type cachedStmt struct {
+	query   string
+	stmt    *sql.Stmt
+	refs    int
+	evicted bool
+}
+
+func newStmtCache(db *sql.DB, size int) *stmtCache {
+	return &stmtCache{
+		db:    db,
+		size:  size,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// acquire returns a prepared statement for query, preparing it if it is not cached.
+// Callers must release the statement when they have finished executing it.
+func (c *stmtCache) acquire(ctx context.Context, query string) (*cachedStmt, error) {
+	c.mu.Lock()
+	if el, ok := c.items[query]; ok {
+		c.ll.MoveToFront(el)
+		cs := el.Value.(*cachedStmt)
+		cs.refs++
+		c.mu.Unlock()
+		return cs, nil
+	}
+	c.mu.Unlock()
+
+	stmt, err := c.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// another caller may have prepared the same query while we were unlocked
+	if el, ok := c.items[query]; ok {
+		_ = stmt.Close()
+		c.ll.MoveToFront(el)
+		cs := el.Value.(*cachedStmt)
+		cs.refs++
+		return cs, nil
+	}
+
+	cs := &cachedStmt{query: query, stmt: stmt, refs: 1}
+	c.items[query] = c.ll.PushFront(cs)
+
+	for c.ll.Len() > c.size {
+		c.evict(c.ll.Back())
+	}
+
+	return cs, nil
+}
+
+// release marks the caller as done with cs, closing it if it has been evicted
+func (c *stmtCache) release(cs *cachedStmt) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cs.refs--
+	if cs.evicted && cs.refs == 0 {
+		_ = cs.stmt.Close()
+	}
+}
+
+// evict removes el from the cache; c.mu must be held
+func (c *stmtCache) evict(el *list.Element) {
+	cs := el.Value.(*cachedStmt)
+	c.ll.Remove(el)
+	delete(c.items, cs.query)
+
+	cs.evicted = true
+	if cs.refs == 0 {
+		_ = cs.stmt.Close()
+	}
+}
+
+// len returns the number of cached statements
+func (c *stmtCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.ll.Len()
+}
+
+// close evicts every cached statement
+func (c *stmtCache) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var errs []error
+	for el := c.ll.Back(); el != nil; el = c.ll.Back() {
+		cs := el.Value.(*cachedStmt)
+		c.ll.Remove(el)
+		delete(c.items, cs.query)
+
+		cs.evicted = true
+		if cs.refs == 0 {
+			errs = append(errs, cs.stmt.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,105 @@
+package nosqlite
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestStore_StatementCache(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := NewStore(helperTempFile(t), WithStatementCacheSize(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	for i := 1; i <= 3; i++ {
+		err := table.Insert(ctx, Foo{Id: i, Name: fmt.Sprintf("cache-%d", i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 1; i <= 3; i++ {
+		val, err := table.QueryOne(ctx, Equal("$.name", fmt.Sprintf("cache-%d", i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if val == nil || val.Name != fmt.Sprintf("cache-%d", i) {
+			t.Errorf("expected cache-%d got %v", i, val)
+		}
+	}
+
+	vals, err := table.QueryMany(ctx, GreaterThanOrEqual("$.name", "cache-2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 2 {
+		t.Errorf("expected 2 got %d", len(vals))
+	}
+
+	count, err := table.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 got %d", count)
+	}
+
+	if got := store.stmts.len(); got != 2 {
+		t.Errorf("expected 2 cached statements got %d", got)
+	}
+}
+
+func TestStore_StatementCacheDisabled(t *testing.T) {
+	store, err := NewStore(helperTempFile(t), WithStatementCacheSize(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer helperCloseStore(t, store)
+
+	if store.stmts != nil {
+		t.Fatal("expected statement cache to be disabled")
+	}
+}
+
+func benchmarkInsert(b *testing.B, cacheSize int) {
+	ctx := context.Background()
+
+	f, err := os.CreateTemp(os.TempDir(), "bench-nosqlite.db")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	store, err := NewStore(f.Name(), WithStatementCacheSize(cacheSize))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+
+	table, err := NewTable[Foo](ctx, store)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := table.Insert(ctx, Foo{Id: i, Name: "bench"})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTable_InsertCached(b *testing.B) {
+	benchmarkInsert(b, defaultStatementCacheSize)
+}
+
+func BenchmarkTable_InsertUncached(b *testing.B) {
+	benchmarkInsert(b, 0)
+}
@@ -1,54 +1,615 @@
 package nosqlite
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	_ "github.com/glebarez/go-sqlite/compat"
 )
 
 // Store represents a store for the database
 type Store struct {
-	db *sql.DB
+	db    *sql.DB
+	codec Codec
+
+	attachMu     sync.Mutex
+	attached     map[string]struct{}
+	maxOpenConns int
+
+	stmtCacheSize int
+	stmts         *stmtCache
+
+	pragmas []pragma
+
+	queryHook QueryHook
+
+	closed atomic.Bool
+
+	maxWriteAttempts int
+
+	journalMode string
+
+	serializeWrites bool
+	// writeLock holds a value while a write holds the lock when writes are serialized
+	writeLock chan struct{}
+}
+
+// journalModes are the journal modes accepted by WithJournalMode
+var journalModes = []string{"DELETE", "TRUNCATE", "PERSIST", "MEMORY", "WAL", "OFF"}
+
+func validateJournalMode(mode string) error {
+	if !slices.Contains(journalModes, mode) {
+		return fmt.Errorf("unsupported journal mode %q", mode)
+	}
+	return nil
+}
+
+// ErrStoreClosed is returned by operations on a store, or its tables, after it has been closed
+var ErrStoreClosed = errors.New("store closed")
+
+// QueryHook is called after each statement run by a table operation with the statement,
+// its arguments, how long it took and any error. For queries the duration covers
+// running the query but not reading its rows.
+type QueryHook func(ctx context.Context, query string, args []any, d time.Duration, err error)
+
+// pragma is a per-connection setting applied when the store is opened
+type pragma struct {
+	name  string
+	value string
+}
+
+func (p pragma) statement() string {
+	return fmt.Sprintf("PRAGMA %s = %s", p.name, p.value)
+}
+
+// withPragmas adds pragmas to the query parameters of a file path so the driver
+// applies them to every connection it opens
+func withPragmas(filePath string, pragmas []pragma) string {
+	if len(pragmas) == 0 {
+		return filePath
+	}
+
+	params := url.Values{}
+	for _, p := range pragmas {
+		params.Add("_pragma", fmt.Sprintf("%s(%s)", p.name, p.value))
+	}
+
+	separator := "?"
+	if strings.Contains(filePath, "?") {
+		separator = "&"
+	}
+	return filePath + separator + params.Encode()
+}
+
+// StoreOption configures a Store
+type StoreOption func(*Store)
+
+// WithCodec sets the codec used to (de)serialize documents for all tables in the store
+func WithCodec(codec Codec) StoreOption {
+	return func(s *Store) {
+		s.codec = codec
+	}
+}
+
+// WithStatementCacheSize sets the number of prepared statements kept in the store's
+// LRU statement cache. A size of zero disables the cache.
+func WithStatementCacheSize(size int) StoreOption {
+	return func(s *Store) {
+		s.stmtCacheSize = size
+	}
+}
+
+// WithCacheSize sets PRAGMA cache_size, the maximum number of pages held in memory per
+// connection, or the number of KiB when negative
+func WithCacheSize(size int) StoreOption {
+	return func(s *Store) {
+		s.pragmas = append(s.pragmas, pragma{name: "cache_size", value: strconv.Itoa(size)})
+	}
+}
+
+// WithMmapSize sets PRAGMA mmap_size, the maximum number of bytes of the database file
+// accessed using memory-mapped I/O per connection
+func WithMmapSize(size int64) StoreOption {
+	return func(s *Store) {
+		s.pragmas = append(s.pragmas, pragma{name: "mmap_size", value: strconv.FormatInt(size, 10)})
+	}
+}
+
+// WithQueryHook sets a hook called after each statement run by table operations, such as
+// for tracing or logging slow queries
+func WithQueryHook(hook QueryHook) StoreOption {
+	return func(s *Store) {
+		s.queryHook = hook
+	}
+}
+
+// WithMaxWriteAttempts sets how many times Insert, Update and Delete are attempted when
+// the database is busy or locked by another writer, backing off between attempts.
+// Defaults to 3, a value of 1 disables retries.
+func WithMaxWriteAttempts(attempts int) StoreOption {
+	return func(s *Store) {
+		s.maxWriteAttempts = max(attempts, 1)
+	}
+}
+
+// WithSerializedWrites serializes writes within the process, so that Insert, Update, Delete
+// and other writes outside a transaction wait for each other rather than contending for the
+// database lock. Transactions, other than read only ones, hold the lock from begin until
+// commit or rollback, so writing outside a transaction while one is open blocks until it ends
+// or the context of the write is done.
+// Writers in other processes are not affected.
+func WithSerializedWrites() StoreOption {
+	return func(s *Store) {
+		s.serializeWrites = true
+	}
+}
+
+// WithJournalMode sets PRAGMA journal_mode, one of DELETE, TRUNCATE, PERSIST, MEMORY, WAL
+// or OFF, in place of the default WAL. If SQLite cannot use the mode, such as WAL on some
+// network filesystems, the database keeps its current mode, see Store.JournalMode.
+func WithJournalMode(mode string) StoreOption {
+	return func(s *Store) {
+		s.journalMode = strings.ToUpper(mode)
+	}
+}
+
+// WithoutWAL uses the rollback journal, journal mode DELETE, rather than WAL
+func WithoutWAL() StoreOption {
+	return WithJournalMode("DELETE")
 }
 
 // NewStore creates a new store with the given file path
-func NewStore(filePath string) (*Store, error) {
-	db, err := sql.Open("sqlite3", filePath)
+func NewStore(filePath string, opts ...StoreOption) (*Store, error) {
+	return NewStoreContext(context.Background(), filePath, opts...)
+}
+
+// NewStoreContext creates a new store with the given file path, aborting if ctx is cancelled
+func NewStoreContext(ctx context.Context, filePath string, opts ...StoreOption) (*Store, error) {
+	options := &Store{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	pragmas := options.pragmas
+	if options.journalMode != "" {
+		if err := validateJournalMode(options.journalMode); err != nil {
+			return nil, err
+		}
+		// journal modes other than WAL only apply to the connection that sets them
+		pragmas = append(slices.Clone(pragmas), pragma{name: "journal_mode", value: options.journalMode})
+	}
+
+	db, err := sql.Open("sqlite3", withPragmas(filePath, pragmas))
 	if err != nil {
 		return nil, err
 	}
 
-	return NewStoreWithDB(db)
+	store, err := NewStoreWithDBContext(ctx, db, opts...)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return store, nil
 }
 
 // NewStoreWithDB creates a new store with the given database
-func NewStoreWithDB(db *sql.DB) (*Store, error) {
+func NewStoreWithDB(db *sql.DB, opts ...StoreOption) (*Store, error) {
+	return NewStoreWithDBContext(context.Background(), db, opts...)
+}
+
+// NewStoreWithDBContext creates a new store with the given database, aborting if ctx is cancelled.
+// Pragmas set by options are only applied to a single connection of db, so should also be
+// configured by the caller when opening db.
+func NewStoreWithDBContext(ctx context.Context, db *sql.DB, opts ...StoreOption) (*Store, error) {
+	store := newStore(db, opts...)
+
+	if err := validateJournalMode(store.journalMode); err != nil {
+		return nil, err
+	}
+
 	// PRAGMA busy_timeout = 5000;
-	_, err := db.Exec("PRAGMA busy_timeout = 5000")
+	_, err := db.ExecContext(ctx, "PRAGMA busy_timeout = 5000")
 	if err != nil {
 		return nil, err
 	}
 
 	// PRAGMA synchronous = NORMAL;
-	_, err = db.Exec("PRAGMA synchronous = NORMAL")
+	_, err = db.ExecContext(ctx, "PRAGMA synchronous = NORMAL")
 	if err != nil {
 		return nil, err
 	}
 
 	// PRAGMA journal_mode = WAL;
-	_, err = db.Exec("PRAGMA journal_mode = WAL")
+	_, err = db.ExecContext(ctx, fmt.Sprintf("PRAGMA journal_mode = %s", store.journalMode))
 	if err != nil {
 		return nil, err
 	}
 
-	return &Store{db: db}, nil
+	for _, p := range store.pragmas {
+		_, err = db.ExecContext(ctx, p.statement())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return store, nil
+}
+
+// WrapDB returns a store using db as is, without setting any pragmas, for callers that
+// configure the connection themselves. Options that set pragmas, such as WithCacheSize and
+// WithJournalMode, have no effect.
+func WrapDB(db *sql.DB, opts ...StoreOption) *Store {
+	return newStore(db, opts...)
+}
+
+// newStore returns a store using db with opts applied, without configuring db
+func newStore(db *sql.DB, opts ...StoreOption) *Store {
+	store := &Store{db: db, codec: JSONCodec{}, stmtCacheSize: defaultStatementCacheSize, maxWriteAttempts: defaultMaxWriteAttempts, journalMode: "WAL"}
+	for _, opt := range opts {
+		opt(store)
+	}
+
+	if store.stmtCacheSize > 0 {
+		store.stmts = newStmtCache(db, store.stmtCacheSize)
+	}
+	if store.serializeWrites {
+		store.writeLock = make(chan struct{}, 1)
+	}
+
+	return store
 }
 
+// Ping verifies the database is still reachable
 func (s *Store) Ping() error {
 	return s.db.Ping()
 }
 
+// PingContext verifies the database is still reachable, aborting if ctx is cancelled
+func (s *Store) PingContext(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// Attach attaches the database file at path to the store under alias. Tables in the
+// attached database can be used by creating them with WithSchema(alias).
+//
+// Attached databases are only visible to the connection that attached them, so while any
+// database is attached the store is limited to a single connection. Attach should be called
+// before the store is used concurrently.
+func (s *Store) Attach(ctx context.Context, path, alias string) error {
+	s.attachMu.Lock()
+	defer s.attachMu.Unlock()
+
+	if len(s.attached) == 0 {
+		s.maxOpenConns = s.db.Stats().MaxOpenConnections
+		s.db.SetMaxOpenConns(1)
+	}
+
+	_, err := s.execContext(ctx, "ATTACH DATABASE ? AS ?", path, alias)
+	if err != nil {
+		if len(s.attached) == 0 {
+			s.db.SetMaxOpenConns(s.maxOpenConns)
+		}
+		return err
+	}
+
+	if s.attached == nil {
+		s.attached = make(map[string]struct{})
+	}
+	s.attached[alias] = struct{}{}
+	return nil
+}
+
+// Detach detaches the database previously attached under alias, restoring the
+// store's connection limit once no databases remain attached
+func (s *Store) Detach(ctx context.Context, alias string) error {
+	s.attachMu.Lock()
+	defer s.attachMu.Unlock()
+
+	_, err := s.execContext(ctx, "DETACH DATABASE ?", alias)
+	if err != nil {
+		return err
+	}
+
+	delete(s.attached, alias)
+	if len(s.attached) == 0 {
+		s.db.SetMaxOpenConns(s.maxOpenConns)
+	}
+	return nil
+}
+
+// Tables returns the names of the tables in the main database that have a data column,
+// as created by NewTable
+func (s *Store) Tables(ctx context.Context) ([]string, error) {
+	rows, err := s.queryContext(ctx, "SELECT m.name FROM sqlite_master m WHERE m.type = 'table' AND m.name NOT LIKE 'sqlite_%' AND EXISTS (SELECT 1 FROM pragma_table_info(m.name) WHERE name = 'data') ORDER BY m.name")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		err = rows.Scan(&name)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// TableCounts returns the number of items in each table returned by Tables
+func (s *Store) TableCounts(ctx context.Context) (map[string]uint64, error) {
+	names, err := s.Tables(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]uint64, len(names))
+	for _, name := range names {
+		var c uint64
+		err = s.queryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", quoteIdentifier(name))).Scan(&c)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count table %s: %w", name, err)
+		}
+		counts[name] = c
+	}
+	return counts, nil
+}
+
+// Reindex rebuilds every index in the database
+func (s *Store) Reindex(ctx context.Context) error {
+	_, err := s.execContext(ctx, "REINDEX")
+	return err
+}
+
+// JournalMode returns the journal mode in use, in lower case as reported by SQLite
+func (s *Store) JournalMode(ctx context.Context) (string, error) {
+	var mode string
+	err := s.queryRowContext(ctx, "PRAGMA journal_mode").Scan(&mode)
+	return mode, err
+}
+
+// UserVersion returns the application-defined schema version stored in the database file
+func (s *Store) UserVersion(ctx context.Context) (int, error) {
+	var version int
+	err := s.queryRowContext(ctx, "PRAGMA user_version").Scan(&version)
+	return version, err
+}
+
+// SetUserVersion stores an application-defined schema version in the database file
+func (s *Store) SetUserVersion(ctx context.Context, version int) error {
+	_, err := s.execContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", version))
+	return err
+}
+
+// Stats returns the connection pool statistics of the underlying database
+func (s *Store) Stats() sql.DBStats {
+	return s.db.Stats()
+}
+
+// PageCount returns the number of pages in the database file
+func (s *Store) PageCount(ctx context.Context) (uint64, error) {
+	var pageCount uint64
+	err := s.queryRowContext(ctx, "PRAGMA page_count").Scan(&pageCount)
+	return pageCount, err
+}
+
+// DatabaseSize returns the size of the database file in bytes, excluding the WAL
+func (s *Store) DatabaseSize(ctx context.Context) (uint64, error) {
+	var size uint64
+	err := s.queryRowContext(ctx, "SELECT page_count * page_size FROM pragma_page_count(), pragma_page_size()").Scan(&size)
+	return size, err
+}
+
 // Close closes the database
 func (s *Store) Close() error {
+	s.closed.Store(true)
+
+	if s.stmts != nil {
+		if err := s.stmts.close(); err != nil {
+			_ = s.db.Close()
+			return err
+		}
+	}
 	return s.db.Close()
 }
+
+// executor runs statements either directly against the store or within a transaction
+type executor interface {
+	execContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	queryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	queryRowContext(ctx context.Context, query string, args ...any) *Row
+}
+
+// Row is the result of Store.QueryRowContext and Transaction.QueryRowContext, holding any
+// error, such as ErrStoreClosed, that prevented the query from running
+type Row struct {
+	*sql.Row
+	err error
+}
+
+// Scan copies the columns of the row into dest, as with sql.Row
+func (r *Row) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.Row.Scan(dest...)
+}
+
+// Err returns the error, if any, encountered running the query
+func (r *Row) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.Row.Err()
+}
+
+// checkOpen returns ErrStoreClosed once the store has been closed
+func (s *Store) checkOpen() error {
+	if s.closed.Load() {
+		return ErrStoreClosed
+	}
+	return nil
+}
+
+// ExecContext executes a query against the store without returning any rows
+func (s *Store) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return s.execContext(ctx, query, args...)
+}
+
+// QueryContext executes a query against the store that returns rows
+func (s *Store) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return s.queryContext(ctx, query, args...)
+}
+
+// QueryRowContext executes a query against the store that returns at most one row
+func (s *Store) QueryRowContext(ctx context.Context, query string, args ...any) *Row {
+	return s.queryRowContext(ctx, query, args...)
+}
+
+const (
+	defaultMaxWriteAttempts = 3
+	retryBaseDelay          = 10 * time.Millisecond
+	retryMaxDelay           = time.Second
+
+	// primary result codes of SQLITE_BUSY and SQLITE_LOCKED
+	sqliteBusy   = 5
+	sqliteLocked = 6
+)
+
+// isBusy reports whether err is SQLITE_BUSY or SQLITE_LOCKED, which are transient while
+// another connection holds a lock
+func isBusy(err error) bool {
+	var sqliteErr interface{ Code() int }
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	// extended result codes keep the primary code in the low byte
+	switch sqliteErr.Code() & 0xff {
+	case sqliteBusy, sqliteLocked:
+		return true
+	}
+	return false
+}
+
+// withRetry calls fn until it succeeds, fails with an error other than isBusy, or has been
+// called maxWriteAttempts times, waiting with jittered exponential backoff between calls
+func (s *Store) withRetry(ctx context.Context, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || !isBusy(err) || attempt+1 >= s.maxWriteAttempts {
+			return err
+		}
+
+		delay := min(retryBaseDelay<<attempt, retryMaxDelay)
+		delay = delay/2 + rand.N(delay/2+1)
+
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+}
+
+// observe calls the store's query hook, if any, for a statement started at start
+func (s *Store) observe(ctx context.Context, query string, args []any, start time.Time, err error) {
+	if s.queryHook != nil {
+		s.queryHook(ctx, query, args, time.Since(start), err)
+	}
+}
+
+// execContext executes query using a cached prepared statement when the cache is enabled
+func (s *Store) execContext(ctx context.Context, query string, args ...any) (res sql.Result, err error) {
+	defer func(start time.Time) { s.observe(ctx, query, args, start, err) }(time.Now())
+
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+
+	unlock, err := s.lockWrites(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if s.stmts == nil {
+		return s.db.ExecContext(ctx, query, args...)
+	}
+
+	cs, err := s.stmts.acquire(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer s.stmts.release(cs)
+
+	return cs.stmt.ExecContext(ctx, args...)
+}
+
+// lockWrites acquires the write lock when writes are serialized, returning the function
+// that releases it, or the error of ctx if it is done first
+func (s *Store) lockWrites(ctx context.Context) (func(), error) {
+	if s.writeLock == nil {
+		return func() {}, nil
+	}
+	select {
+	case s.writeLock <- struct{}{}:
+		return func() { <-s.writeLock }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// queryContext runs query using a cached prepared statement when the cache is enabled
+func (s *Store) queryContext(ctx context.Context, query string, args ...any) (rows *sql.Rows, err error) {
+	defer func(start time.Time) { s.observe(ctx, query, args, start, err) }(time.Now())
+
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+
+	if s.stmts == nil {
+		return s.db.QueryContext(ctx, query, args...)
+	}
+
+	cs, err := s.stmts.acquire(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	// open rows keep the underlying statement alive after release
+	defer s.stmts.release(cs)
+
+	return cs.stmt.QueryContext(ctx, args...)
+}
+
+// queryRowContext runs query using a cached prepared statement when the cache is enabled
+func (s *Store) queryRowContext(ctx context.Context, query string, args ...any) (r *Row) {
+	defer func(start time.Time) { s.observe(ctx, query, args, start, r.Err()) }(time.Now())
+
+	if err := s.checkOpen(); err != nil {
+		return &Row{err: err}
+	}
+
+	if s.stmts == nil {
+		return &Row{Row: s.db.QueryRowContext(ctx, query, args...)}
+	}
+
+	cs, err := s.stmts.acquire(ctx, query)
+	if err != nil {
+		return &Row{err: err}
+	}
+	defer s.stmts.release(cs)
+
+	return &Row{Row: cs.stmt.QueryRowContext(ctx, args...)}
+}
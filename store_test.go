@@ -1,6 +1,15 @@
 package nosqlite
 
-import "testing"
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
 
 func TestNewStore(t *testing.T) {
 	fileName := helperTempFile(t)
@@ -17,3 +26,834 @@ func TestNewStore(t *testing.T) {
 		}
 	}()
 }
+
+func TestNewStoreContext(t *testing.T) {
+	fileName := helperTempFile(t)
+
+	store, err := NewStoreContext(context.Background(), fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewStoreContextCancelled(t *testing.T) {
+	fileName := helperTempFile(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	store, err := NewStoreContext(ctx, fileName)
+	if err == nil {
+		_ = store.Close()
+		t.Fatal("expected error got nil")
+	}
+}
+
+func TestWrapDB(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := sql.Open("sqlite3", helperTempFile(t)+"?_pragma=journal_mode(TRUNCATE)&_pragma=busy_timeout(1000)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := WrapDB(db)
+	defer helperCloseStore(t, store)
+
+	// the caller's journal mode is kept rather than replaced with WAL
+	mode, err := store.JournalMode(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode != "truncate" {
+		t.Errorf("expected truncate got %s", mode)
+	}
+
+	table := helperTable[Foo](ctx, t, store)
+
+	err = table.Insert(ctx, Foo{Id: 1, Name: "wrapped"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = table.Update(ctx, Equal("$.id", 1), Foo{Id: 1, Name: "updated"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	val, err := table.Get(ctx, Equal("$.id", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val.Name != "updated" {
+		t.Errorf("expected updated got %s", val.Name)
+	}
+
+	err = table.Delete(ctx, Equal("$.id", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := table.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("expected 0 got %d", count)
+	}
+}
+
+func TestStore_Stats(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	_, err := table.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stats := store.Stats()
+	if stats.OpenConnections < 1 {
+		t.Errorf("expected at least 1 open connection got %d", stats.OpenConnections)
+	}
+}
+
+func TestStore_DatabaseSize(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	_ = helperTable[Foo](ctx, t, store)
+
+	pageCount, err := store.PageCount(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pageCount < 1 {
+		t.Errorf("expected at least 1 page got %d", pageCount)
+	}
+
+	size, err := store.DatabaseSize(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size == 0 || size%pageCount != 0 {
+		t.Errorf("expected a multiple of %d pages got %d bytes", pageCount, size)
+	}
+}
+
+func TestStore_Attach(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	otherFile := helperTempFile(t)
+	other := helperOpenStoreWithFile(t, otherFile)
+
+	otherTable := helperTable[Foo](ctx, t, other)
+	for i := 1; i <= 3; i++ {
+		err := otherTable.Insert(ctx, Foo{Id: i})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	helperCloseStore(t, other)
+
+	table := helperTable[Foo](ctx, t, store)
+	err := table.Insert(ctx, Foo{Id: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.Attach(ctx, otherFile, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attachedTable, err := NewTable[Foo](ctx, store, WithSchema("other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mainCount, err := table.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attachedCount, err := attachedTable.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mainCount+attachedCount != 4 {
+		t.Errorf("expected 4 got %d", mainCount+attachedCount)
+	}
+
+	err = store.Detach(ctx, "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = attachedTable.Count(ctx)
+	if err == nil {
+		t.Fatal("expected error got nil")
+	}
+
+	if got := store.Stats().MaxOpenConnections; got != 0 {
+		t.Errorf("expected unlimited connections got %d", got)
+	}
+}
+
+func TestStore_TableCounts(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	fooTable := helperTable[Foo](ctx, t, store)
+	idTable := helperTable[IDOne](ctx, t, store)
+
+	_, err := store.db.ExecContext(ctx, "CREATE TABLE other (id INTEGER)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		err := fooTable.Insert(ctx, Foo{Id: i})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = idTable.Insert(ctx, IDOne{ID: "one"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tables, err := store.Tables(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 2 || tables[0] != "nosqlite_foo" || tables[1] != "nosqlite_idone" {
+		t.Errorf("expected [nosqlite_foo nosqlite_idone] got %v", tables)
+	}
+
+	counts, err := store.TableCounts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 2 || counts["nosqlite_foo"] != 3 || counts["nosqlite_idone"] != 1 {
+		t.Errorf("expected map[nosqlite_foo:3 nosqlite_idone:1] got %v", counts)
+	}
+}
+
+func TestStore_PingContext(t *testing.T) {
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	err := store.PingContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = store.PingContext(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v got %v", context.Canceled, err)
+	}
+}
+
+func TestStore_WithCacheSize(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := NewStore(helperTempFile(t), WithCacheSize(-4096), WithMmapSize(1<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer helperCloseStore(t, store)
+
+	// check every connection in the pool has the setting
+	conns := make([]*sql.Conn, 3)
+	for i := range conns {
+		conns[i], err = store.db.Conn(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, conn := range conns {
+		var cacheSize int
+		err = conn.QueryRowContext(ctx, "PRAGMA cache_size").Scan(&cacheSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cacheSize != -4096 {
+			t.Errorf("expected -4096 got %d", cacheSize)
+		}
+
+		err = conn.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestWithPragmas(t *testing.T) {
+	pragmas := []pragma{{name: "cache_size", value: "-4096"}}
+
+	tests := []struct {
+		filePath string
+		expected string
+	}{
+		{"test.db", "test.db?_pragma=cache_size%28-4096%29"},
+		{"file:test.db?mode=rwc", "file:test.db?mode=rwc&_pragma=cache_size%28-4096%29"},
+	}
+
+	for _, test := range tests {
+		if got := withPragmas(test.filePath, pragmas); got != test.expected {
+			t.Errorf("got = %v, want %v", got, test.expected)
+		}
+	}
+
+	if got := withPragmas("test.db", nil); got != "test.db" {
+		t.Errorf("got = %v, want %v", got, "test.db")
+	}
+}
+
+func TestStore_WithQueryHook(t *testing.T) {
+	ctx := context.Background()
+
+	type call struct {
+		query string
+		args  []any
+		err   error
+	}
+	var calls []call
+
+	store, err := NewStore(helperTempFile(t), WithQueryHook(func(ctx context.Context, query string, args []any, d time.Duration, err error) {
+		calls = append(calls, call{query: query, args: args, err: err})
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	if len(calls) != 1 || !strings.HasPrefix(calls[0].query, "CREATE TABLE") {
+		t.Fatalf("expected table creation to be observed got %v", calls)
+	}
+	calls = nil
+
+	err = table.Insert(ctx, Foo{Id: 1, Name: "hooked"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(calls) != 1 {
+		t.Fatalf("expected 1 call got %d", len(calls))
+	}
+	if want := "INSERT INTO `nosqlite_foo` (data) VALUES (?)"; calls[0].query != want {
+		t.Errorf("got = %v, want %v", calls[0].query, want)
+	}
+	if len(calls[0].args) != 1 || calls[0].args[0] != `{"id":1,"name":"hooked","bar":{}}` {
+		t.Errorf("unexpected args %v", calls[0].args)
+	}
+	if calls[0].err != nil {
+		t.Errorf("expected nil error got %v", calls[0].err)
+	}
+
+	err = store.WithTx(ctx, func(tx *Transaction) error {
+		_, err := table.WithTransaction(tx).Count(ctx)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(calls) != 2 || !strings.HasPrefix(calls[1].query, "SELECT COUNT(*)") {
+		t.Errorf("expected count query to be observed got %v", calls)
+	}
+}
+
+func TestStore_ExecContext(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	_, err := store.ExecContext(ctx, "PRAGMA user_version = 7")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var version int
+	err = store.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 7 {
+		t.Errorf("expected 7 got %d", version)
+	}
+
+	rows, err := store.QueryContext(ctx, "SELECT value FROM json_each(?)", "[1, 2, 3]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var sum int
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			t.Fatal(err)
+		}
+		sum += v
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if sum != 6 {
+		t.Errorf("expected 6 got %d", sum)
+	}
+}
+
+func TestStore_UserVersion(t *testing.T) {
+	ctx := context.Background()
+
+	fileName := helperTempFile(t)
+	store := helperOpenStoreWithFile(t, fileName)
+
+	version, err := store.UserVersion(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 0 {
+		t.Errorf("expected 0 got %d", version)
+	}
+
+	err = store.SetUserVersion(ctx, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	helperCloseStore(t, store)
+
+	// the version is persisted in the database file
+	store = helperOpenStoreWithFile(t, fileName)
+	defer helperCloseStore(t, store)
+
+	version, err = store.UserVersion(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 3 {
+		t.Errorf("expected 3 got %d", version)
+	}
+}
+
+func TestStore_Closed(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	table := helperTable[Foo](ctx, t, store)
+
+	helperCloseStore(t, store)
+
+	err := table.Insert(ctx, Foo{Id: 1, Name: "closed"})
+	if !errors.Is(err, ErrStoreClosed) {
+		t.Errorf("expected %v got %v", ErrStoreClosed, err)
+	}
+
+	_, err = table.Count(ctx)
+	if !errors.Is(err, ErrStoreClosed) {
+		t.Errorf("expected %v got %v", ErrStoreClosed, err)
+	}
+
+	_, err = table.QueryMany(ctx, All())
+	if !errors.Is(err, ErrStoreClosed) {
+		t.Errorf("expected %v got %v", ErrStoreClosed, err)
+	}
+
+	err = store.WithTx(ctx, func(tx *Transaction) error {
+		return nil
+	})
+	if !errors.Is(err, ErrStoreClosed) {
+		t.Errorf("expected %v got %v", ErrStoreClosed, err)
+	}
+
+	_, err = NewTable[Document](ctx, store)
+	if !errors.Is(err, ErrStoreClosed) {
+		t.Errorf("expected %v got %v", ErrStoreClosed, err)
+	}
+
+	_, err = table.ExplainQueryPlan(ctx, All())
+	if !errors.Is(err, ErrStoreClosed) {
+		t.Errorf("expected %v got %v", ErrStoreClosed, err)
+	}
+
+	err = table.Reindex(ctx)
+	if !errors.Is(err, ErrStoreClosed) {
+		t.Errorf("expected %v got %v", ErrStoreClosed, err)
+	}
+
+	var c int
+	err = store.QueryRowContext(ctx, "SELECT 1").Scan(&c)
+	if !errors.Is(err, ErrStoreClosed) {
+		t.Errorf("expected %v got %v", ErrStoreClosed, err)
+	}
+
+	storeCalls := map[string]func() error{
+		"Tables": func() error {
+			_, err := store.Tables(ctx)
+			return err
+		},
+		"TableCounts": func() error {
+			_, err := store.TableCounts(ctx)
+			return err
+		},
+		"JournalMode": func() error {
+			_, err := store.JournalMode(ctx)
+			return err
+		},
+		"UserVersion": func() error {
+			_, err := store.UserVersion(ctx)
+			return err
+		},
+		"PageCount": func() error {
+			_, err := store.PageCount(ctx)
+			return err
+		},
+		"DatabaseSize": func() error {
+			_, err := store.DatabaseSize(ctx)
+			return err
+		},
+		"SetUserVersion": func() error {
+			return store.SetUserVersion(ctx, 1)
+		},
+		"Reindex": func() error {
+			return store.Reindex(ctx)
+		},
+		"Attach": func() error {
+			return store.Attach(ctx, helperTempFile(t), "other")
+		},
+		"Detach": func() error {
+			return store.Detach(ctx, "other")
+		},
+	}
+	for name, call := range storeCalls {
+		if err := call(); !errors.Is(err, ErrStoreClosed) {
+			t.Errorf("%s: expected %v got %v", name, ErrStoreClosed, err)
+		}
+	}
+}
+
+type codeError int
+
+func (e codeError) Error() string { return fmt.Sprintf("sqlite error %d", int(e)) }
+func (e codeError) Code() int     { return int(e) }
+
+func TestIsBusy(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{codeError(5), true},
+		{codeError(6), true},
+		{fmt.Errorf("wrapped: %w", codeError(5|(2<<8))), true},
+		{codeError(19), false},
+		{errors.New("other"), false},
+		{nil, false},
+	}
+
+	for _, test := range tests {
+		if got := isBusy(test.err); got != test.expected {
+			t.Errorf("isBusy(%v) = %v, want %v", test.err, got, test.expected)
+		}
+	}
+}
+
+func TestStore_WithMaxWriteAttempts(t *testing.T) {
+	ctx := context.Background()
+
+	fileName := helperTempFile(t)
+
+	store, err := NewStore(fileName, WithMaxWriteAttempts(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	// fail immediately rather than waiting for the lock inside SQLite
+	store.db.SetMaxOpenConns(1)
+	_, err = store.db.ExecContext(ctx, "PRAGMA busy_timeout = 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	other := helperOpenStoreWithFile(t, fileName)
+	defer helperCloseStore(t, other)
+
+	tx, err := other.BeginImmediate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	released := make(chan error, 1)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		released <- tx.Commit()
+	}()
+
+	err = table.Insert(ctx, Foo{Id: 1, Name: "retried"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = <-released; err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := table.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 got %d", count)
+	}
+}
+
+func TestStore_WithRetryAttempts(t *testing.T) {
+	ctx := context.Background()
+
+	store := &Store{maxWriteAttempts: 3}
+
+	calls := 0
+	err := store.withRetry(ctx, func() error {
+		calls++
+		return codeError(5)
+	})
+	if !isBusy(err) {
+		t.Errorf("expected busy error got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 got %d", calls)
+	}
+
+	calls = 0
+	err = store.withRetry(ctx, func() error {
+		calls++
+		return errors.New("other")
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("expected a single failed call got %d calls and %v", calls, err)
+	}
+}
+
+func TestStore_WithSerializedWritesContext(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := NewStore(helperTempFile(t), WithSerializedWrites())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	tx, err := store.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// writes waiting for the open transaction give up when their context is done
+	err = table.WithTimeout(50*time.Millisecond).Insert(ctx, Foo{Id: 1, Name: "blocked"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %v got %v", context.DeadlineExceeded, err)
+	}
+
+	// store level writes wait for the transaction too
+	timeout, cancelTimeout := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancelTimeout()
+	err = store.SetUserVersion(timeout, 2)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %v got %v", context.DeadlineExceeded, err)
+	}
+
+	// reads do not wait for it
+	_, _, err = table.WithTimeout(50*time.Millisecond).QueryPage(ctx, All(), 10, 0)
+	if err != nil {
+		t.Errorf("expected no error got %v", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = store.BeginImmediate(cancelled)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v got %v", context.Canceled, err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = table.Insert(ctx, Foo{Id: 2, Name: "after"})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStore_WithSerializedWrites(t *testing.T) {
+	ctx := context.Background()
+
+	// without busy_timeout contending writers fail immediately with SQLITE_BUSY
+	store, err := NewStore(helperTempFile(t)+"?_pragma=busy_timeout(0)", WithSerializedWrites(), WithMaxWriteAttempts(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	const writers = 8
+	const writes = 200
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*writes)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 1; i <= writes; i++ {
+				id := w*writes + i
+				if i%2 == 0 {
+					errs <- table.Insert(ctx, Foo{Id: id, Name: "insert"})
+					continue
+				}
+				errs <- store.WithTx(ctx, func(tx *Transaction) error {
+					txTable := table.WithTransaction(tx)
+					if err := txTable.Insert(ctx, Foo{Id: id, Name: "tx"}); err != nil {
+						return err
+					}
+					return txTable.Update(ctx, Equal("$.id", id), Foo{Id: id, Name: "updated"})
+				})
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("expected no error got %v", err)
+		}
+	}
+
+	count, err := table.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != writers*writes {
+		t.Errorf("expected %d got %d", writers*writes, count)
+	}
+}
+
+func TestStore_WithJournalMode(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := NewStore(helperTempFile(t), WithJournalMode("memory"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer helperCloseStore(t, store)
+
+	// check every connection in the pool uses the mode
+	conns := make([]*sql.Conn, 3)
+	for i := range conns {
+		conns[i], err = store.db.Conn(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, conn := range conns {
+		var mode string
+		err = conn.QueryRowContext(ctx, "PRAGMA journal_mode").Scan(&mode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mode != "memory" {
+			t.Errorf("expected memory got %s", mode)
+		}
+		if err = conn.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	table := helperTable[Foo](ctx, t, store)
+
+	err = table.Insert(ctx, Foo{Id: 1, Name: "created"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = table.Update(ctx, Equal("$.id", 1), Foo{Id: 1, Name: "updated"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	val, err := table.Get(ctx, Equal("$.id", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val.Name != "updated" {
+		t.Errorf("expected updated got %s", val.Name)
+	}
+
+	err = table.Delete(ctx, Equal("$.id", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := table.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("expected 0 got %d", count)
+	}
+
+	_, err = NewStore(helperTempFile(t), WithJournalMode("wal; DROP TABLE x"))
+	if err == nil {
+		t.Error("expected error for unsupported journal mode got nil")
+	}
+}
+
+func TestStore_WithoutWAL(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := NewStore(helperTempFile(t), WithoutWAL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer helperCloseStore(t, store)
+
+	mode, err := store.JournalMode(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode != "delete" {
+		t.Errorf("expected delete got %s", mode)
+	}
+}
@@ -1,24 +1,64 @@
 package nosqlite
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash/crc32"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/dioad/reflect"
 )
 
+// ErrNotFound is returned when no item matches a clause
+var ErrNotFound = errors.New("not found")
+
+// ErrInvalidTableName is returned when a table name cannot be safely used in a statement
+var ErrInvalidTableName = errors.New("invalid table name")
+
 // Table represents a table in the database
 type Table[T any] struct {
-	store *Store
+	store     *Store
+	codec     Codec
+	schema    string
+	timeout   time.Duration
+	scope     Clause
+	validator func(T) error
+	ttl       time.Duration
 
 	// Name of the table
 	Name string
 }
 
+// TableOption configures a Table
+type TableOption func(*tableOptions)
+
+type tableOptions struct {
+	codec  Codec
+	schema string
+}
+
+// WithTableCodec sets the codec used to (de)serialize documents for a single table,
+// overriding the codec configured on the store
+func WithTableCodec(codec Codec) TableOption {
+	return func(o *tableOptions) {
+		o.codec = codec
+	}
+}
+
+// WithSchema places the table in the named schema, such as a database attached with
+// Store.Attach, rather than the main database
+func WithSchema(schema string) TableOption {
+	return func(o *tableOptions) {
+		o.schema = schema
+	}
+}
+
 func tableName[T any]() string {
 	t, _ := reflect.Name[T]()
 
@@ -28,11 +68,8 @@ func tableName[T any]() string {
 }
 
 // NewTable creates a new table with the given type T
-func NewTable[T any](ctx context.Context, store *Store) (*Table[T], error) {
-	table := &Table[T]{
-		store: store,
-		Name:  tableName[T](),
-	}
+func NewTable[T any](ctx context.Context, store *Store, opts ...TableOption) (*Table[T], error) {
+	table := newTable[T](store, opts...)
 
 	err := table.CreateTable(ctx)
 	if err != nil {
@@ -41,6 +78,125 @@ func NewTable[T any](ctx context.Context, store *Store) (*Table[T], error) {
 	return table, nil
 }
 
+// NewTableTx creates a new table with the given type T within tx, so it is only created if
+// tx commits. The returned table is not bound to tx, use WithTransaction for that.
+func NewTableTx[T any](ctx context.Context, tx *Transaction, opts ...TableOption) (*Table[T], error) {
+	table := newTable[T](tx.store, opts...)
+
+	_, err := tx.execContext(ctx, table.createTableStatement(table.Name))
+	if err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+func newTable[T any](store *Store, opts ...TableOption) *Table[T] {
+	options := &tableOptions{codec: store.codec}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return &Table[T]{
+		store:  store,
+		codec:  options.codec,
+		schema: options.schema,
+		Name:   tableName[T](),
+	}
+}
+
+// WithTimeout returns a view of the table whose operations are bounded by timeout,
+// in addition to any deadline of the context they are called with.
+// Operations through WithTransaction are not bounded.
+func (n *Table[T]) WithTimeout(timeout time.Duration) *Table[T] {
+	t := *n
+	t.timeout = timeout
+	return &t
+}
+
+// WithValidator returns a view of the table that calls validator with each document before
+// it is inserted, updated or upserted, aborting the write with any error it returns
+func (n *Table[T]) WithValidator(validator func(T) error) *Table[T] {
+	t := *n
+	t.validator = validator
+	return &t
+}
+
+// validateDocument checks data with the table's validator, if any
+func (n *Table[T]) validateDocument(data T) error {
+	if n.validator == nil {
+		return nil
+	}
+	return n.validator(data)
+}
+
+// Scoped returns a view of the table whose operations only see items matching scope, such
+// as those of a single tenant. scope is combined with the clause of every query, update and
+// delete, and with any scope the table already has. Items inserted through the view are not
+// checked against scope.
+func (n *Table[T]) Scoped(scope Clause) *Table[T] {
+	t := *n
+	if n.scope != nil {
+		scope = And(n.scope, scope)
+	}
+	t.scope = scope
+	return &t
+}
+
+// expiresAtField holds the time an item expires, in Unix milliseconds, for tables with a TTL.
+// It is reserved to the package so it does not collide with a field of the stored type.
+const expiresAtField = "$._nosqlite_expires_at"
+
+// WithTTL returns a view of the table that records an expiry ttl from now in the reserved
+// _nosqlite_expires_at field of each item it inserts or updates, and whose queries, updates
+// and deletes exclude expired items. Items without the field never expire. Expired items
+// remain stored until removed with PurgeExpired.
+func (n *Table[T]) WithTTL(ttl time.Duration) *Table[T] {
+	t := *n
+	t.ttl = ttl
+	return &t
+}
+
+// notExpired returns a clause matching items without an expiry or that expire after now
+func notExpired(now time.Time) Clause {
+	return Or(Is(expiresAtField, nil), GreaterThan(expiresAtField, now.UnixMilli()))
+}
+
+// scoped combines clause with the table's scope, if any, and excludes expired items if the
+// table has a TTL
+func (n *Table[T]) scoped(clause Clause) Clause {
+	if n.ttl > 0 {
+		clause = And(notExpired(time.Now()), clause)
+	}
+	if n.scope == nil {
+		return clause
+	}
+	return And(n.scope, clause)
+}
+
+// documentValue returns the expression and arguments to write the encoded document b,
+// recording its expiry if the table has a TTL
+func (n *Table[T]) documentValue(b []byte) (string, []any) {
+	value, args := n.expiringValue("?")
+	return value, append([]any{string(b)}, args...)
+}
+
+// expiringValue returns the expression and arguments to write the document expression value,
+// recording its expiry if the table has a TTL
+func (n *Table[T]) expiringValue(value string) (string, []any) {
+	if n.ttl <= 0 {
+		return value, nil
+	}
+	return fmt.Sprintf("json_set(%s, '%s', ?)", value, expiresAtField), []any{time.Now().Add(n.ttl).UnixMilli()}
+}
+
+// operationContext derives a context bounded by the table's timeout, if one is set
+func (n *Table[T]) operationContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if n.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, n.timeout)
+}
+
 func escapeFieldName(field string) string {
 	_, after, _ := strings.Cut(field, ".")
 
@@ -64,30 +220,229 @@ func constructIndexName(tableName string, fields ...string) string {
 	return fmt.Sprintf("idx_%s_%s", tableName, joinedParts)
 }
 
+// quoteIdentifier quotes name for use as an identifier in a statement
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// qualifiedName returns name quoted and qualified with the table's schema, if any
+func (n *Table[T]) qualifiedName(name string) string {
+	if n.schema == "" {
+		return quoteIdentifier(name)
+	}
+	return quoteIdentifier(n.schema) + "." + quoteIdentifier(name)
+}
+
+// tableRef returns the quoted, schema-qualified name of the table for use in statements
+func (n *Table[T]) tableRef() string {
+	return n.qualifiedName(n.Name)
+}
+
 func (n *Table[T]) indexName(fields ...string) string {
 	return constructIndexName(n.Name, fields...)
 }
 
 // CreateTable creates the table if it does not exist
 func (n *Table[T]) CreateTable(ctx context.Context) error {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
 	return n.createTableWithName(ctx, n.Name)
 }
 
 func (n *Table[T]) createTableWithName(ctx context.Context, tableName string) error {
-	createStatement := fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s` (data jsonb)", tableName)
-	_, err := n.store.db.ExecContext(ctx, createStatement)
+	_, err := n.store.execContext(ctx, n.createTableStatement(tableName))
 	return err
 }
 
+func (n *Table[T]) createTableStatement(tableName string) string {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (data jsonb)", n.qualifiedName(tableName))
+}
+
+// validateTableName accepts names made of letters, digits and underscores, which is how
+// table names are derived from types, excluding those reserved by SQLite
+func validateTableName(name string) error {
+	if name == "" || strings.HasPrefix(strings.ToLower(name), "sqlite_") {
+		return fmt.Errorf("%w: %q", ErrInvalidTableName, name)
+	}
+	for _, r := range name {
+		if r != '_' && (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return fmt.Errorf("%w: %q contains %q", ErrInvalidTableName, name, r)
+		}
+	}
+	return nil
+}
+
+// Rename renames the table to newName, keeping its data, along with the indexes created
+// for it by this package so their names continue to match the table
+func (n *Table[T]) Rename(ctx context.Context, newName string) error {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	if err := validateTableName(newName); err != nil {
+		return err
+	}
+
+	oldPrefix := constructIndexName(n.Name)
+	newPrefix := constructIndexName(newName)
+
+	err := n.store.WithTx(ctx, func(tx *Transaction) error {
+		_, err := tx.execContext(ctx, fmt.Sprintf("%s %s RENAME TO %s", "ALTER TABLE", n.tableRef(), quoteIdentifier(newName)))
+		if err != nil {
+			return err
+		}
+
+		// SQLite cannot rename an index, so each is dropped and created again under its new name
+		indexQuery := fmt.Sprintf("%s name, sql FROM %s WHERE type = 'index' AND tbl_name = ? AND name LIKE ? ESCAPE '%s' AND sql IS NOT NULL", "SELECT", n.qualifiedName("sqlite_master"), likeEscapeChar)
+		rows, err := tx.queryContext(ctx, indexQuery, newName, EscapeLike(oldPrefix)+"%")
+		if err != nil {
+			return err
+		}
+		indexes := map[string]string{}
+		for rows.Next() {
+			var name, createStatement string
+			err = rows.Scan(&name, &createStatement)
+			if err != nil {
+				_ = rows.Close()
+				return err
+			}
+			indexes[name] = createStatement
+		}
+		if err = errors.Join(rows.Err(), rows.Close()); err != nil {
+			return err
+		}
+
+		for name, createStatement := range indexes {
+			newIndexName := newPrefix + strings.TrimPrefix(name, oldPrefix)
+
+			_, err = tx.execContext(ctx, fmt.Sprintf("%s %s", "DROP INDEX", n.qualifiedName(name)))
+			if err != nil {
+				return err
+			}
+			_, err = tx.execContext(ctx, strings.Replace(createStatement, quoteIdentifier(name), n.qualifiedName(newIndexName), 1))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	n.Name = newName
+	return nil
+}
+
 // Count returns the number of items in the table
 func (n *Table[T]) Count(ctx context.Context) (uint64, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	return n.count(ctx, n.store)
+}
+
+func (n *Table[T]) count(ctx context.Context, db executor) (uint64, error) {
+	if n.scope != nil || n.ttl > 0 {
+		return n.countWhere(ctx, db, n.scoped(All()))
+	}
 	var c uint64
-	count := n.store.db.QueryRowContext(ctx, fmt.Sprintf("%s COUNT(*) AS count FROM `%s`", "SELECT", n.Name))
+	count := db.queryRowContext(ctx, fmt.Sprintf("%s COUNT(*) AS count FROM %s", "SELECT", n.tableRef()))
 	err := count.Scan(&c)
 	return c, err
 }
 
+// countWhere returns the number of items matching clause
+func (n *Table[T]) countWhere(ctx context.Context, db executor, clause Clause) (uint64, error) {
+	if err := clauseErr(clause); err != nil {
+		return 0, err
+	}
+	var c uint64
+	queryStatement := fmt.Sprintf("%s COUNT(*) AS count FROM %s WHERE %s", "SELECT", n.tableRef(), clause.Clause())
+	err := db.queryRowContext(ctx, queryStatement, clause.Values()...).Scan(&c)
+	return c, err
+}
+
+// RowIDRange returns the lowest and highest rowid in the table, for splitting work into
+// rowid windows, or zero for both if the table is empty
+func (n *Table[T]) RowIDRange(ctx context.Context) (int64, int64, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	clause := n.scoped(All())
+	if err := clauseErr(clause); err != nil {
+		return 0, 0, err
+	}
+
+	var minRowid, maxRowid int64
+	queryStatement := fmt.Sprintf("%s COALESCE(MIN(rowid), 0), COALESCE(MAX(rowid), 0) FROM %s WHERE %s", "SELECT", n.tableRef(), clause.Clause())
+	err := n.store.queryRowContext(ctx, queryStatement, clause.Values()...).Scan(&minRowid, &maxRowid)
+	return minRowid, maxRowid, err
+}
+
+// DistinctCount returns the number of distinct non-null values of field among the items matching clause
+func (n *Table[T]) DistinctCount(ctx context.Context, field string, clause Clause) (uint64, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	clause = n.scoped(clause)
+	if err := errors.Join(validateFieldPath(field), clauseErr(clause)); err != nil {
+		return 0, err
+	}
+
+	var c uint64
+	queryStatement := fmt.Sprintf("%s COUNT(DISTINCT %s) FROM %s WHERE %s", "SELECT", jsonField(field), n.tableRef(), clause.Clause())
+	err := n.store.queryRowContext(ctx, queryStatement, clause.Values()...).Scan(&c)
+	return c, err
+}
+
+// CollectField returns the value of field from each item matching clause, in insertion order,
+// aggregated by SQLite into a single JSON array so no document is decoded. Items without field
+// give nil, and values are decoded as by encoding/json, so numbers are float64 and objects are
+// map[string]any.
+func (n *Table[T]) CollectField(ctx context.Context, field string, clause Clause) ([]any, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	clause = n.scoped(clause)
+	if err := errors.Join(validateFieldPath(field), clauseErr(clause)); err != nil {
+		return nil, err
+	}
+
+	var b []byte
+	queryStatement := fmt.Sprintf("%s json_group_array(data->'%s' ORDER BY rowid) FROM %s WHERE %s", "SELECT", jsonPath(field), n.tableRef(), clause.Clause())
+	err := n.store.queryRowContext(ctx, queryStatement, clause.Values()...).Scan(&b)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []any
+	if err := json.Unmarshal(b, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// StorageSize returns the total size in bytes of the stored documents matching clause
+func (n *Table[T]) StorageSize(ctx context.Context, clause Clause) (int64, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	clause = n.scoped(clause)
+	if err := clauseErr(clause); err != nil {
+		return 0, err
+	}
+
+	var size int64
+	queryStatement := fmt.Sprintf("%s COALESCE(SUM(length(CAST(data AS BLOB))), 0) FROM %s WHERE %s", "SELECT", n.tableRef(), clause.Clause())
+	err := n.store.queryRowContext(ctx, queryStatement, clause.Values()...).Scan(&size)
+	return size, err
+}
+
 func (n *Table[T]) CreateIndexes(ctx context.Context, indexes ...[]string) ([]string, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
 	var err error
 	indexNames := make([]string, len(indexes))
 	for i, fields := range indexes {
@@ -99,111 +454,1409 @@ func (n *Table[T]) CreateIndexes(ctx context.Context, indexes ...[]string) ([]st
 	return indexNames, nil
 }
 
+// CreateIndexesTx creates an index for each set of fields in indexes within a single
+// transaction, so if any index cannot be created none of them are
+func (n *Table[T]) CreateIndexesTx(ctx context.Context, indexes ...[]string) ([]string, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	indexNames := make([]string, len(indexes))
+	err := n.store.WithTx(ctx, func(tx *Transaction) error {
+		var err error
+		for i, fields := range indexes {
+			indexNames[i], err = n.createIndex(ctx, tx, false, fields...)
+			if err != nil {
+				return fmt.Errorf("failed to create index for fields %v: %w", fields, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return indexNames, nil
+}
+
 // CreateIndex creates an index on the given fields
 func (n *Table[T]) CreateIndex(ctx context.Context, fields ...string) (string, error) {
-	indexName := n.indexName(fields...)
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
 
-	indexFields := make([]string, len(fields))
+	return n.createIndex(ctx, n.store, false, fields...)
+}
+
+// CreateUniqueIndex creates an index on the given fields that rejects items whose fields
+// all equal those of an existing item
+func (n *Table[T]) CreateUniqueIndex(ctx context.Context, fields ...string) (string, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	return n.createIndex(ctx, n.store, true, fields...)
+}
+
+// CreateIndexOrdered creates an index on the fields of columns, each sorted in the direction
+// of its Order, e.g. CreateIndexOrdered(ctx, Asc("$.name"), Desc("$.ts")). SQLite can read an
+// index in either direction, so directions only matter for queries ordering on several fields
+// in mixed directions, such as ORDER BY name ASC, ts DESC.
+func (n *Table[T]) CreateIndexOrdered(ctx context.Context, columns ...Order) (string, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	return n.createOrderedIndex(ctx, n.store, false, columns)
+}
+
+func (n *Table[T]) createIndex(ctx context.Context, db executor, unique bool, fields ...string) (string, error) {
+	columns := make([]Order, len(fields))
 	for i, field := range fields {
-		indexFields[i] = fmt.Sprintf("data->>'%s'", field)
+		columns[i] = Asc(field)
+	}
+	return n.createOrderedIndex(ctx, db, unique, columns)
+}
+
+func (n *Table[T]) createOrderedIndex(ctx context.Context, db executor, unique bool, columns []Order) (string, error) {
+	columns = normalizeIndexColumns(columns)
+
+	nameParts := make([]string, len(columns))
+	for i, column := range columns {
+		nameParts[i] = escapeFieldName(column.Field)
+		if column.Direction == descending {
+			nameParts[i] += "_desc"
+		}
+	}
+	indexName := fmt.Sprintf("idx_%s_%s", n.Name, strings.Join(nameParts, "_"))
+	createIndex := "CREATE INDEX"
+	if unique {
+		indexName += "_unique"
+		createIndex = "CREATE UNIQUE INDEX"
+	}
+
+	indexFields := make([]string, len(columns))
+	for i, column := range columns {
+		if err := validateFieldPath(column.Field); err != nil {
+			return indexName, err
+		}
+		if column.Field == "" || column.Nulls != "" {
+			return indexName, errors.New("index columns must have a field and cannot order nulls")
+		}
+		indexFields[i] = jsonField(column.Field)
+		if column.Direction == descending {
+			indexFields[i] += " DESC"
+		}
 	}
 
 	indexes := strings.Join(indexFields, ", ")
 
-	createIndexStatement := fmt.Sprintf("CREATE INDEX IF NOT EXISTS `%s` ON `%s` (%s)", indexName, n.Name, indexes)
-	_, err := n.store.db.ExecContext(ctx, createIndexStatement)
+	existing, err := n.existingIndexes(ctx, db)
+	if err != nil {
+		return indexName, err
+	}
+	for name, createStatement := range existing {
+		if strings.HasPrefix(createStatement, createIndex+" ") && strings.HasSuffix(createStatement, fmt.Sprintf(" (%s)", indexes)) {
+			return name, nil
+		}
+	}
+	for name := range existing {
+		// index names are case-insensitive, so fields differing only in case, such as $.Name
+		// and $.name, would otherwise share a name and the second index not be created
+		if strings.EqualFold(name, indexName) {
+			indexName = fmt.Sprintf("%s_%08x", indexName, crc32.ChecksumIEEE([]byte(indexes)))
+			break
+		}
+	}
+
+	createIndexStatement := fmt.Sprintf("%s IF NOT EXISTS %s ON `%s` (%s)", createIndex, n.qualifiedName(indexName), n.Name, indexes)
+	_, err = db.execContext(ctx, createIndexStatement)
 	return indexName, err
 }
 
-// hasIndex returns true if the index exists
-func (n *Table[T]) hasIndex(ctx context.Context, indexName string) (bool, error) {
-	_, err := n.store.db.ExecContext(ctx, "SELECT name FROM sqlite_master WHERE type='index' AND tbl_name=? AND name=?", n.Name, indexName)
-	if err != nil {
-		return false, err
+// normalizeIndexColumns trims surrounding whitespace from the field of each column and
+// removes columns repeating an earlier field
+func normalizeIndexColumns(columns []Order) []Order {
+	normalized := make([]Order, 0, len(columns))
+	for _, column := range columns {
+		column.Field = strings.TrimSpace(column.Field)
+		if !slices.ContainsFunc(normalized, func(o Order) bool { return o.Field == column.Field }) {
+			normalized = append(normalized, column)
+		}
 	}
-	return true, nil
+	return normalized
 }
 
-// Delete removes items from the table that match the given clause
-func (n *Table[T]) Delete(ctx context.Context, clause Clause) error {
-	deleteStatement := fmt.Sprintf("%s `%s` WHERE %s", "DELETE FROM", n.Name, clause.Clause())
-	_, err := n.store.db.ExecContext(ctx, deleteStatement, clause.Values()...)
-	return err
+// existingIndexes returns the create statement of each index on the table by name
+func (n *Table[T]) existingIndexes(ctx context.Context, db executor) (map[string]string, error) {
+	indexQuery := fmt.Sprintf("%s name, sql FROM %s WHERE type = 'index' AND tbl_name = ? AND sql IS NOT NULL", "SELECT", n.qualifiedName("sqlite_master"))
+	rows, err := db.queryContext(ctx, indexQuery, n.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	indexes := map[string]string{}
+	for rows.Next() {
+		var name, createStatement string
+		if err := rows.Scan(&name, &createStatement); err != nil {
+			return nil, err
+		}
+		indexes[name] = createStatement
+	}
+	return indexes, rows.Err()
 }
 
-// Insert adds a new item to the table
-func (n *Table[T]) Insert(ctx context.Context, data T) error {
-	b, err := json.Marshal(data)
+// CreatePartialIndex creates an index on the given fields covering only the items matching where.
+// Index predicates cannot use parameters so the values of where are rendered as literals, and
+// SQLite will only use the index for queries whose own predicate implies where.
+func (n *Table[T]) CreatePartialIndex(ctx context.Context, fields []string, where Clause) (string, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	errs := []error{clauseErr(where)}
+	for _, field := range fields {
+		errs = append(errs, validateFieldPath(field))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return "", err
+	}
+
+	predicate, err := interpolate(where, sqlLiteral)
 	if err != nil {
+		return "", fmt.Errorf("failed to render partial index predicate: %w", err)
+	}
+
+	indexName := fmt.Sprintf("%s_partial_%08x", n.indexName(fields...), crc32.ChecksumIEEE([]byte(predicate)))
+
+	indexFields := make([]string, len(fields))
+	for i, field := range fields {
+		indexFields[i] = jsonField(field)
+	}
+
+	indexes := strings.Join(indexFields, ", ")
+
+	createIndexStatement := fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON `%s` (%s) WHERE %s", n.qualifiedName(indexName), n.Name, indexes, predicate)
+	_, err = n.store.execContext(ctx, createIndexStatement)
+	return indexName, err
+}
+
+// generatedColumnTypes are the column types accepted by AddGeneratedColumn
+var generatedColumnTypes = []string{"", "INTEGER", "REAL", "TEXT", "BLOB", "NUMERIC"}
+
+// AddGeneratedColumn adds a column called name to the table whose value is field extracted
+// from each document, converted to sqlType (one of INTEGER, REAL, TEXT, BLOB, NUMERIC, or
+// empty for no conversion). SQLite only allows virtual columns to be added to an existing
+// table so the value is computed on read, index it with CreateColumnIndex to store it.
+func (n *Table[T]) AddGeneratedColumn(ctx context.Context, name, field, sqlType string) error {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	if err := validateFieldPath(field); err != nil {
 		return err
 	}
-	insertStatement := fmt.Sprintf("%s `%s` (data) VALUES (?)", "INSERT INTO", n.Name)
-	_, err = n.store.db.ExecContext(ctx, insertStatement, string(b))
+
+	sqlType = strings.ToUpper(sqlType)
+	if !slices.Contains(generatedColumnTypes, sqlType) {
+		return fmt.Errorf("unsupported column type %q", sqlType)
+	}
+
+	alterStatement := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s GENERATED ALWAYS AS (%s) VIRTUAL", n.tableRef(), quoteIdentifier(name), sqlType, jsonField(field))
+	_, err := n.store.execContext(ctx, alterStatement)
 	return err
 }
 
-// QueryOne returns a single item from the table
-func (n *Table[T]) QueryOne(ctx context.Context, clause Clause) (*T, error) {
-	var data string
+// CreateColumnIndex creates an index on the given columns, such as those added with AddGeneratedColumn
+func (n *Table[T]) CreateColumnIndex(ctx context.Context, columns ...string) (string, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
 
-	queryStatement := fmt.Sprintf("%s data FROM `%s` WHERE %s", "SELECT", n.Name, clause.Clause())
-	row := n.store.db.QueryRowContext(ctx, queryStatement, clause.Values()...)
-	err := row.Scan(&data)
-	if errors.Is(err, sql.ErrNoRows) {
-		return nil, nil
-	}
-	if err != nil {
-		return nil, err
+	indexName := fmt.Sprintf("idx_%s_%s", n.Name, strings.Join(columns, "_"))
+
+	indexColumns := make([]string, len(columns))
+	for i, column := range columns {
+		indexColumns[i] = quoteIdentifier(column)
 	}
-	var result T
-	err = json.Unmarshal([]byte(data), &result)
-	return &result, err
+
+	createIndexStatement := fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON `%s` (%s)", n.qualifiedName(indexName), n.Name, strings.Join(indexColumns, ", "))
+	_, err := n.store.execContext(ctx, createIndexStatement)
+	return indexName, err
 }
 
-func (n *Table[T]) All(ctx context.Context) ([]T, error) {
-	return n.QueryMany(ctx, All())
+// Reindex rebuilds every index on the table
+func (n *Table[T]) Reindex(ctx context.Context) error {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	_, err := n.store.execContext(ctx, fmt.Sprintf("%s %s", "REINDEX", n.tableRef()))
+	return err
 }
 
-// QueryMany returns multiple items from the table
-// can we use http://doug-martin.github.io/goqu/ for this?
-func (n *Table[T]) QueryMany(ctx context.Context, clause Clause) ([]T, error) {
-	var data string
-	var results []T
+// RebuildIndex drops and creates again, in a single transaction, the indexes created on fields
+// by CreateIndex, CreateUniqueIndex, CreateIndexOrdered in any direction, and CreatePartialIndex,
+// for example so SQLite reconsiders a partial index once the data it covers has changed.
+// Returns ErrNotFound if there are no such indexes.
+func (n *Table[T]) RebuildIndex(ctx context.Context, fields ...string) error {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
 
-	queryStatement := fmt.Sprintf("%s data FROM `%s` WHERE %s", "SELECT", n.Name, clause.Clause())
-	rows, err := n.store.db.QueryContext(ctx, queryStatement, clause.Values()...)
-	if errors.Is(err, sql.ErrNoRows) {
-		return results, nil
+	columns := make([]Order, len(fields))
+	for i, field := range fields {
+		columns[i] = Asc(field)
 	}
+	columns = normalizeIndexColumns(columns)
 
-	if err != nil {
-		return nil, err
+	expressions := make([]string, len(columns))
+	for i, column := range columns {
+		if err := validateFieldPath(column.Field); err != nil {
+			return err
+		}
+		expressions[i] = jsonField(column.Field)
 	}
-	defer func() { _ = rows.Close() }()
 
-	for rows.Next() {
-		err = rows.Scan(&data)
+	return n.store.WithTx(ctx, func(tx *Transaction) error {
+		existing, err := n.existingIndexes(ctx, tx)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		var result T
-		err = json.Unmarshal([]byte(data), &result)
-		if err != nil {
-			return nil, err
+
+		rebuilt := 0
+		for name, createStatement := range existing {
+			if !indexesExpressions(createStatement, expressions) {
+				continue
+			}
+
+			_, err = tx.execContext(ctx, fmt.Sprintf("%s %s", "DROP INDEX", n.qualifiedName(name)))
+			if err != nil {
+				return err
+			}
+			_, err = tx.execContext(ctx, strings.Replace(createStatement, quoteIdentifier(name), n.qualifiedName(name), 1))
+			if err != nil {
+				return err
+			}
+			rebuilt++
+		}
+		if rebuilt == 0 {
+			return fmt.Errorf("index on %v: %w", fields, ErrNotFound)
 		}
-		results = append(results, result)
+		return nil
+	})
+}
+
+// indexesExpressions reports whether createStatement creates an index on exactly expressions,
+// in order and in either direction, with or without a predicate
+func indexesExpressions(createStatement string, expressions []string) bool {
+	_, rest, ok := strings.Cut(createStatement, " ON ")
+	if !ok {
+		return false
 	}
-	return results, nil
+	// table names cannot contain parentheses, so the first opens the indexed expressions
+	_, rest, ok = strings.Cut(rest, "(")
+	if !ok {
+		return false
+	}
+
+	for i, expression := range expressions {
+		if rest, ok = strings.CutPrefix(rest, expression); !ok {
+			return false
+		}
+		rest = strings.TrimPrefix(rest, " DESC")
+
+		separator := ", "
+		if i == len(expressions)-1 {
+			separator = ")"
+		}
+		if rest, ok = strings.CutPrefix(rest, separator); !ok {
+			return false
+		}
+	}
+	return rest == "" || strings.HasPrefix(rest, " WHERE ")
 }
 
-// Update changes one or more items in the table
-func (n *Table[T]) Update(ctx context.Context, clause Clause, newVal T) error {
-	b, err := json.Marshal(newVal)
+// hasIndex returns true if the index exists
+func (n *Table[T]) hasIndex(ctx context.Context, indexName string) (bool, error) {
+	var name string
+	err := n.store.queryRowContext(ctx, "SELECT name FROM sqlite_master WHERE type='index' AND tbl_name=? AND name=?", n.Name, indexName).Scan(&name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
 	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Delete removes items from the table that match the given clause
+func (n *Table[T]) Delete(ctx context.Context, clause Clause) error {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	return n.store.withRetry(ctx, func() error {
+		_, err := n.delete(ctx, n.store, clause)
+		return err
+	})
+}
+
+// delete removes items matching clause and returns the number of items removed
+func (n *Table[T]) delete(ctx context.Context, db executor, clause Clause) (int64, error) {
+	clause = n.scoped(clause)
+	if err := clauseErr(clause); err != nil {
+		return 0, err
+	}
+	deleteStatement := fmt.Sprintf("%s %s WHERE %s", "DELETE FROM", n.tableRef(), clause.Clause())
+	res, err := db.execContext(ctx, deleteStatement, clause.Values()...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// DeleteLimit removes at most limit items matching clause, oldest first, returning the
+// number of items removed. Deleting in batches keeps each write transaction short.
+func (n *Table[T]) DeleteLimit(ctx context.Context, clause Clause, limit uint64) (int64, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	clause = n.scoped(clause)
+	if err := clauseErr(clause); err != nil {
+		return 0, err
+	}
+	deleteStatement := fmt.Sprintf("%s %s WHERE rowid IN (SELECT rowid FROM %s WHERE %s ORDER BY rowid LIMIT ?)", "DELETE FROM", n.tableRef(), n.tableRef(), clause.Clause())
+	res, err := n.store.execContext(ctx, deleteStatement, append(slices.Clone(clause.Values()), int64(limit))...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// PurgeExpired removes items whose expiry, as recorded by a view from WithTTL, has passed,
+// returning the number of items removed. Only items within the table's scope are removed.
+func (n *Table[T]) PurgeExpired(ctx context.Context) (int64, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	clause := LessThanOrEqual(expiresAtField, time.Now().UnixMilli())
+	if n.scope != nil {
+		clause = And(n.scope, clause)
+	}
+	if err := clauseErr(clause); err != nil {
+		return 0, err
+	}
+
+	var removed int64
+	deleteStatement := fmt.Sprintf("%s %s WHERE %s", "DELETE FROM", n.tableRef(), clause.Clause())
+	err := n.store.withRetry(ctx, func() error {
+		res, err := n.store.execContext(ctx, deleteStatement, clause.Values()...)
+		if err != nil {
+			return err
+		}
+		removed, err = res.RowsAffected()
+		return err
+	})
+	return removed, err
+}
+
+// maxDeleteByIDsChunk keeps each DELETE within SQLite's default limit of 999 parameters
+const maxDeleteByIDsChunk = 999
+
+// DeleteByIDs removes items from table whose idField is one of ids, returning the number of
+// items removed. ids are deleted in chunks within a single transaction.
+func DeleteByIDs[T any, K any](ctx context.Context, table *Table[T], idField string, ids []K) (int64, error) {
+	ctx, cancel := table.operationContext(ctx)
+	defer cancel()
+
+	var total int64
+
+	err := table.store.WithTx(ctx, func(tx *Transaction) error {
+		for start := 0; start < len(ids); start += maxDeleteByIDsChunk {
+			chunk := ids[start:min(start+maxDeleteByIDsChunk, len(ids))]
+
+			values := make([]any, len(chunk))
+			for i, id := range chunk {
+				values[i] = id
+			}
+
+			affected, err := table.delete(ctx, tx, In(idField, values...))
+			if err != nil {
+				return err
+			}
+			total += affected
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// CopyTo inserts transform of each item in src into dst, in insertion order, within a
+// single transaction, returning the number of items copied. src and dst must belong to
+// the same store. Only items already in src when the copy starts are copied, so src and
+// dst may be the same table.
+func CopyTo[T any, U any](ctx context.Context, src *Table[T], dst *Table[U], transform func(T) (U, error)) (int64, error) {
+	ctx, cancel := src.operationContext(ctx)
+	defer cancel()
+
+	if src.store != dst.store {
+		return 0, errors.New("tables belong to different stores")
+	}
+
+	clause := src.scoped(All())
+	if err := clauseErr(clause); err != nil {
+		return 0, err
+	}
+
+	var copied int64
+
+	err := src.store.WithTx(ctx, func(tx *Transaction) error {
+		// rows inserted into dst must not be read back when dst shares src's table
+		var maxRowID int64
+		err := tx.queryRowContext(ctx, fmt.Sprintf("%s COALESCE(MAX(rowid), 0) FROM %s", "SELECT", src.tableRef())).Scan(&maxRowID)
+		if err != nil {
+			return err
+		}
+
+		queryStatement := fmt.Sprintf("%s data FROM %s WHERE (%s) AND rowid <= ? ORDER BY rowid", "SELECT", src.tableRef(), clause.Clause())
+		rows, err := tx.queryContext(ctx, queryStatement, append(slices.Clone(clause.Values()), maxRowID)...)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = rows.Close() }()
+
+		var data string
+		for rows.Next() {
+			err = rows.Scan(&data)
+			if err != nil {
+				return err
+			}
+			var item T
+			err = src.codec.Unmarshal([]byte(data), &item)
+			if err != nil {
+				return err
+			}
+			transformed, err := transform(item)
+			if err != nil {
+				return fmt.Errorf("failed to transform item %d: %w", copied, err)
+			}
+			err = dst.insert(ctx, tx, transformed)
+			if err != nil {
+				return err
+			}
+			copied++
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return 0, err
+	}
+	return copied, nil
+}
+
+// Insert adds a new item to the table
+func (n *Table[T]) Insert(ctx context.Context, data T) error {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	return n.store.withRetry(ctx, func() error {
+		return n.insert(ctx, n.store, data)
+	})
+}
+
+func (n *Table[T]) insert(ctx context.Context, db executor, data T) error {
+	if err := n.validateDocument(data); err != nil {
 		return err
 	}
-	updateStatement := fmt.Sprintf("%s %s SET data = ? WHERE %s", "UPDATE", n.Name, clause.Clause())
-	params := append([]any{string(b)}, clause.Values()...)
-	_, err = n.store.db.ExecContext(ctx, updateStatement, params...)
+	b, err := n.codec.Marshal(data)
+	if err != nil {
+		return err
+	}
+	value, args := n.documentValue(b)
+	insertStatement := fmt.Sprintf("%s %s (data) VALUES (%s)", "INSERT INTO", n.tableRef(), value)
+	_, err = db.execContext(ctx, insertStatement, args...)
 	return err
 }
+
+// InsertSeq adds each item yielded by seq to the table within a single transaction, so either
+// all of them are inserted or, if any fails, none are, and returns the number inserted. seq has
+// the signature of iter.Seq[T], letting generated items be inserted without collecting them
+// into a slice first.
+func (n *Table[T]) InsertSeq(ctx context.Context, seq func(yield func(T) bool)) (int64, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	var count int64
+
+	err := n.store.WithTx(ctx, func(tx *Transaction) error {
+		var err error
+		seq(func(data T) bool {
+			err = n.insert(ctx, tx, data)
+			if err != nil {
+				err = fmt.Errorf("failed to insert item %d: %w", count, err)
+				return false
+			}
+			count++
+			return true
+		})
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// InsertWithRowID adds a new item to the table with the given rowid, for example to restore
+// items exactly as they were exported. Returns a constraint error if rowid is already used.
+func (n *Table[T]) InsertWithRowID(ctx context.Context, rowid int64, data T) error {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	if err := n.validateDocument(data); err != nil {
+		return err
+	}
+	b, err := n.codec.Marshal(data)
+	if err != nil {
+		return err
+	}
+	value, args := n.documentValue(b)
+	insertStatement := fmt.Sprintf("%s %s (rowid, data) VALUES (?, %s)", "INSERT INTO", n.tableRef(), value)
+	return n.store.withRetry(ctx, func() error {
+		_, err := n.store.execContext(ctx, insertStatement, append([]any{rowid}, args...)...)
+		return err
+	})
+}
+
+// InsertIgnore adds a new item to the table unless it conflicts with an existing item on a
+// unique index, such as one created with CreateUniqueIndex, in which case it does nothing
+func (n *Table[T]) InsertIgnore(ctx context.Context, data T) error {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	if err := n.validateDocument(data); err != nil {
+		return err
+	}
+	b, err := n.codec.Marshal(data)
+	if err != nil {
+		return err
+	}
+	value, args := n.documentValue(b)
+	insertStatement := fmt.Sprintf("%s %s (data) VALUES (%s) ON CONFLICT DO NOTHING", "INSERT INTO", n.tableRef(), value)
+	return n.store.withRetry(ctx, func() error {
+		_, err := n.store.execContext(ctx, insertStatement, args...)
+		return err
+	})
+}
+
+// ReplaceAll atomically replaces the contents of the table with data. Readers outside
+// the transaction see either the previous or the new contents.
+func (n *Table[T]) ReplaceAll(ctx context.Context, data []T) error {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	return n.store.WithTx(ctx, func(tx *Transaction) error {
+		_, err := n.delete(ctx, tx, All())
+		if err != nil {
+			return err
+		}
+
+		for _, d := range data {
+			err = n.insert(ctx, tx, d)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// QueryOne returns a single item from the table
+func (n *Table[T]) QueryOne(ctx context.Context, clause Clause) (*T, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	return n.queryOne(ctx, n.store, clause)
+}
+
+func (n *Table[T]) queryOne(ctx context.Context, db executor, clause Clause) (*T, error) {
+	clause = n.scoped(clause)
+	if err := clauseErr(clause); err != nil {
+		return nil, err
+	}
+	queryStatement := fmt.Sprintf("%s data FROM %s WHERE %s", "SELECT", n.tableRef(), clause.Clause())
+	return n.queryRow(ctx, db, queryStatement, clause.Values()...)
+}
+
+// Get returns a single item from the table, or ErrNotFound if no item matches clause
+func (n *Table[T]) Get(ctx context.Context, clause Clause) (T, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	return n.get(ctx, n.store, clause)
+}
+
+func (n *Table[T]) get(ctx context.Context, db executor, clause Clause) (T, error) {
+	var zero T
+
+	result, err := n.queryOne(ctx, db, clause)
+	if err != nil {
+		return zero, err
+	}
+	if result == nil {
+		return zero, ErrNotFound
+	}
+	return *result, nil
+}
+
+// QueryField returns the value of field from the first item matching clause without decoding
+// the item. Values are returned as stored by SQLite, i.e. as a string, int64, float64 or nil,
+// with nested objects and arrays returned as their JSON text. found is false if no item
+// matches clause, while a matching item without field returns a nil value.
+func (n *Table[T]) QueryField(ctx context.Context, clause Clause, field string) (value any, found bool, err error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	clause = n.scoped(clause)
+	if err := errors.Join(validateFieldPath(field), clauseErr(clause)); err != nil {
+		return nil, false, err
+	}
+
+	queryStatement := fmt.Sprintf("%s %s FROM %s WHERE %s LIMIT 1", "SELECT", jsonField(field), n.tableRef(), clause.Clause())
+	err = n.store.queryRowContext(ctx, queryStatement, clause.Values()...).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// QueryFirst returns the first item from the table matching clause after applying order.
+// Items that are otherwise equal are returned in insertion order.
+func (n *Table[T]) QueryFirst(ctx context.Context, clause Clause, order ...Order) (*T, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	return n.queryFirst(ctx, n.store, clause, order...)
+}
+
+func (n *Table[T]) queryFirst(ctx context.Context, db executor, clause Clause, order ...Order) (*T, error) {
+	clause = n.scoped(clause)
+	if err := errors.Join(clauseErr(clause), validateOrders(order)); err != nil {
+		return nil, err
+	}
+	orderBy := orderByClause(append(slices.Clone(order), rowidOrder)...)
+	queryStatement := fmt.Sprintf("%s data FROM %s WHERE %s %s LIMIT 1", "SELECT", n.tableRef(), clause.Clause(), orderBy)
+	return n.queryRow(ctx, db, queryStatement, clause.Values()...)
+}
+
+// queryRow runs queryStatement and decodes the data column of the first row
+func (n *Table[T]) queryRow(ctx context.Context, db executor, queryStatement string, args ...any) (*T, error) {
+	var data string
+
+	row := db.queryRowContext(ctx, queryStatement, args...)
+	err := row.Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var result T
+	err = n.codec.Unmarshal([]byte(data), &result)
+	return &result, err
+}
+
+// All returns every item in the table
+func (n *Table[T]) All(ctx context.Context) ([]T, error) {
+	return n.QueryMany(ctx, All())
+}
+
+// QueryMany returns multiple items from the table
+// can we use http://doug-martin.github.io/goqu/ for this?
+func (n *Table[T]) QueryMany(ctx context.Context, clause Clause) ([]T, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	return n.queryMany(ctx, n.store, clause)
+}
+
+func (n *Table[T]) queryMany(ctx context.Context, db executor, clause Clause) ([]T, error) {
+	var results []T
+	err := n.queryManyInto(ctx, db, clause, &results)
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// QueryManyTimed returns the items matching clause, as QueryMany, along with how long the
+// query took, including reading and decoding its rows
+func (n *Table[T]) QueryManyTimed(ctx context.Context, clause Clause) ([]T, time.Duration, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	start := time.Now()
+	results, err := n.queryMany(ctx, n.store, clause)
+	elapsed := time.Since(start)
+	if err != nil {
+		return nil, elapsed, err
+	}
+	return results, elapsed, nil
+}
+
+// QueryUnion returns the items matching any of clauses, running a query per clause combined
+// with UNION so that each can use its own index, unlike a single Or. Identical documents are
+// returned once, and items are not returned in any particular order.
+func (n *Table[T]) QueryUnion(ctx context.Context, clauses ...Clause) ([]T, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	if len(clauses) == 0 {
+		return nil, nil
+	}
+
+	selects := make([]string, len(clauses))
+	var args []any
+	var errs []error
+	for i, clause := range clauses {
+		clause = n.scoped(clause)
+		errs = append(errs, clauseErr(clause))
+		selects[i] = fmt.Sprintf("%s data FROM %s WHERE %s", "SELECT", n.tableRef(), clause.Clause())
+		args = append(args, clause.Values()...)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	var results []T
+	queryStatement := strings.Join(selects, " UNION ")
+	err := n.queryInto(ctx, n.store, &results, queryStatement, args...)
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// QueryManyInto replaces the contents of dst with the items matching clause, reusing
+// its capacity to avoid allocating a new slice on every call
+func (n *Table[T]) QueryManyInto(ctx context.Context, clause Clause, dst *[]T) error {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	*dst = (*dst)[:0]
+	return n.queryManyInto(ctx, n.store, clause, dst)
+}
+
+// queryManyInto appends the items matching clause to dst
+func (n *Table[T]) queryManyInto(ctx context.Context, db executor, clause Clause, dst *[]T) error {
+	clause = n.scoped(clause)
+	if err := clauseErr(clause); err != nil {
+		return err
+	}
+	queryStatement := fmt.Sprintf("%s data FROM %s WHERE %s", "SELECT", n.tableRef(), clause.Clause())
+	return n.queryInto(ctx, db, dst, queryStatement, clause.Values()...)
+}
+
+// queryInto runs queryStatement and appends the decoded data column of each row to dst
+func (n *Table[T]) queryInto(ctx context.Context, db executor, dst *[]T, queryStatement string, args ...any) error {
+	var data string
+
+	rows, err := db.queryContext(ctx, queryStatement, args...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		err = rows.Scan(&data)
+		if err != nil {
+			return err
+		}
+		var result T
+		err = n.codec.Unmarshal([]byte(data), &result)
+		if err != nil {
+			return err
+		}
+		*dst = append(*dst, result)
+	}
+	return rows.Err()
+}
+
+// ForEachBatch calls fn with successive batches of up to batchSize items matching clause,
+// in insertion order, stopping at the first error returned by fn. Each batch is read with
+// a separate query so only one batch is held in memory at a time. The batch slice is
+// reused between calls, so fn must copy it to keep items beyond the call.
+func (n *Table[T]) ForEachBatch(ctx context.Context, clause Clause, batchSize int, fn func([]T) error) error {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	if batchSize <= 0 {
+		return fmt.Errorf("invalid batch size %d", batchSize)
+	}
+
+	clause = n.scoped(clause)
+	if err := clauseErr(clause); err != nil {
+		return err
+	}
+
+	queryStatement := fmt.Sprintf("%s rowid, data FROM %s WHERE %s AND rowid > ? ORDER BY rowid LIMIT ?", "SELECT", n.tableRef(), clause.Clause())
+
+	var lastRowid int64
+	batch := make([]T, 0, batchSize)
+	for {
+		batch = batch[:0]
+
+		rows, err := n.store.queryContext(ctx, queryStatement, append(slices.Clone(clause.Values()), lastRowid, batchSize)...)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var data string
+			err = rows.Scan(&lastRowid, &data)
+			if err != nil {
+				_ = rows.Close()
+				return err
+			}
+			var result T
+			err = n.codec.Unmarshal([]byte(data), &result)
+			if err != nil {
+				_ = rows.Close()
+				return err
+			}
+			batch = append(batch, result)
+		}
+		if err = errors.Join(rows.Err(), rows.Close()); err != nil {
+			return err
+		}
+
+		if len(batch) == 0 {
+			return nil
+		}
+		err = fn(batch)
+		if err != nil {
+			return err
+		}
+		if len(batch) < batchSize {
+			return nil
+		}
+	}
+}
+
+// Stream sends each item matching clause on the returned item channel from a new goroutine,
+// closing it once every item has been sent, an error occurs or ctx is cancelled. The error,
+// if any, is then sent on the error channel, which is closed after the item channel. The
+// caller must either receive every item or cancel ctx for the goroutine to exit.
+func (n *Table[T]) Stream(ctx context.Context, clause Clause) (<-chan T, <-chan error) {
+	items := make(chan T)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+
+		err := n.stream(ctx, clause, items)
+		close(items)
+		if err != nil {
+			errs <- err
+		}
+	}()
+
+	return items, errs
+}
+
+func (n *Table[T]) stream(ctx context.Context, clause Clause, items chan<- T) error {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	clause = n.scoped(clause)
+	if err := clauseErr(clause); err != nil {
+		return err
+	}
+
+	queryStatement := fmt.Sprintf("%s data FROM %s WHERE %s", "SELECT", n.tableRef(), clause.Clause())
+	rows, err := n.store.queryContext(ctx, queryStatement, clause.Values()...)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var data string
+	for rows.Next() {
+		err = rows.Scan(&data)
+		if err != nil {
+			return err
+		}
+		var result T
+		err = n.codec.Unmarshal([]byte(data), &result)
+		if err != nil {
+			return err
+		}
+
+		select {
+		case items <- result:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return rows.Err()
+}
+
+// Latest returns the count most recently inserted items, newest first
+func (n *Table[T]) Latest(ctx context.Context, count uint64) ([]T, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	clause := n.scoped(All())
+	if err := clauseErr(clause); err != nil {
+		return nil, err
+	}
+
+	var items []T
+	queryStatement := fmt.Sprintf("%s data FROM %s WHERE %s ORDER BY rowid DESC LIMIT ?", "SELECT", n.tableRef(), clause.Clause())
+	err := n.queryInto(ctx, n.store, &items, queryStatement, append(slices.Clone(clause.Values()), int64(count))...)
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// QueryPage returns up to limit items matching clause, in insertion order, after skipping
+// offset of them, along with the total number of items matching clause. A limit of 0 returns
+// every item after offset. Both are read within one transaction so they are consistent with
+// each other.
+func (n *Table[T]) QueryPage(ctx context.Context, clause Clause, limit, offset uint64) ([]T, uint64, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	clause = n.scoped(clause)
+	if err := clauseErr(clause); err != nil {
+		return nil, 0, err
+	}
+
+	// SQLite only accepts OFFSET after LIMIT, a negative LIMIT means no limit
+	sqlLimit := int64(-1)
+	if limit > 0 {
+		sqlLimit = int64(limit)
+	}
+
+	// a read only transaction does not wait for the write lock
+	tx, err := n.store.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	total, err := n.countWhere(ctx, tx, clause)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var items []T
+	queryStatement := fmt.Sprintf("%s data FROM %s WHERE %s ORDER BY rowid LIMIT ? OFFSET ?", "SELECT", n.tableRef(), clause.Clause())
+	err = n.queryInto(ctx, tx, &items, queryStatement, append(slices.Clone(clause.Values()), sqlLimit, int64(offset))...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// QueryManyOrdered returns up to limit items matching clause sorted by orders, after skipping
+// offset of them. A limit of 0 returns every item after offset. Items that are otherwise equal
+// are returned in insertion order so that pages are stable.
+func (n *Table[T]) QueryManyOrdered(ctx context.Context, clause Clause, orders []Order, limit, offset uint64) ([]T, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	return n.queryManyOrdered(ctx, n.store, clause, orders, limit, offset)
+}
+
+func (n *Table[T]) queryManyOrdered(ctx context.Context, db executor, clause Clause, orders []Order, limit, offset uint64) ([]T, error) {
+	clause = n.scoped(clause)
+	if err := errors.Join(clauseErr(clause), validateOrders(orders)); err != nil {
+		return nil, err
+	}
+
+	// SQLite only accepts OFFSET after LIMIT, a negative LIMIT means no limit
+	sqlLimit := int64(-1)
+	if limit > 0 {
+		sqlLimit = int64(limit)
+	}
+
+	var items []T
+	orderBy := orderByClause(append(slices.Clone(orders), rowidOrder)...)
+	queryStatement := fmt.Sprintf("%s data FROM %s WHERE %s %s LIMIT ? OFFSET ?", "SELECT", n.tableRef(), clause.Clause(), orderBy)
+	err := n.queryInto(ctx, db, &items, queryStatement, append(slices.Clone(clause.Values()), sqlLimit, int64(offset))...)
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// ExplainQueryPlan returns the detail of each step SQLite would take to run QueryMany
+// with clause, without running it. Useful for checking whether an index is used.
+func (n *Table[T]) ExplainQueryPlan(ctx context.Context, clause Clause) ([]string, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	clause = n.scoped(clause)
+	if err := clauseErr(clause); err != nil {
+		return nil, err
+	}
+
+	queryStatement := fmt.Sprintf("%s data FROM %s WHERE %s", "EXPLAIN QUERY PLAN SELECT", n.tableRef(), clause.Clause())
+	rows, err := n.store.queryContext(ctx, queryStatement, clause.Values()...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var details []string
+	for rows.Next() {
+		var id, parent, notUsed int
+		var detail string
+		err = rows.Scan(&id, &parent, &notUsed, &detail)
+		if err != nil {
+			return nil, err
+		}
+		details = append(details, detail)
+	}
+	return details, rows.Err()
+}
+
+// Validate returns the rowids of items whose data cannot be decoded into T, such as
+// documents truncated or corrupted by writes from outside the store. A scoped view cannot
+// tell which scope invalid JSON belongs to, so only reports items within its scope whose
+// JSON is valid but does not decode.
+func (n *Table[T]) Validate(ctx context.Context) ([]int64, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	clause := n.scoped(All())
+	if err := clauseErr(clause); err != nil {
+		return nil, err
+	}
+	where := clause.Clause()
+	if !isEmptyClause(clause) {
+		// CASE guarantees the clause is only evaluated for valid JSON, which it would reject
+		where = fmt.Sprintf("CASE WHEN json_valid(data) THEN %s ELSE FALSE END", where)
+	}
+
+	queryStatement := fmt.Sprintf("%s rowid, data FROM %s WHERE %s ORDER BY rowid", "SELECT", n.tableRef(), where)
+	rows, err := n.store.queryContext(ctx, queryStatement, clause.Values()...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var invalid []int64
+	for rows.Next() {
+		var rowid int64
+		var data []byte
+		err = rows.Scan(&rowid, &data)
+		if err != nil {
+			return nil, err
+		}
+		var result T
+		if data == nil || n.codec.Unmarshal(data, &result) != nil {
+			invalid = append(invalid, rowid)
+		}
+	}
+	return invalid, rows.Err()
+}
+
+// Update changes one or more items in the table
+func (n *Table[T]) Update(ctx context.Context, clause Clause, newVal T) error {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	return n.store.withRetry(ctx, func() error {
+		_, err := n.update(ctx, n.store, clause, newVal)
+		return err
+	})
+}
+
+// update changes items matching clause and returns the number of items changed
+func (n *Table[T]) update(ctx context.Context, db executor, clause Clause, newVal T) (int64, error) {
+	clause = n.scoped(clause)
+	if err := clauseErr(clause); err != nil {
+		return 0, err
+	}
+	if err := n.validateDocument(newVal); err != nil {
+		return 0, err
+	}
+	b, err := n.codec.Marshal(newVal)
+	if err != nil {
+		return 0, err
+	}
+	value, params := n.documentValue(b)
+	updateStatement := fmt.Sprintf("%s %s SET data = %s WHERE %s", "UPDATE", n.tableRef(), value, clause.Clause())
+	params = append(params, clause.Values()...)
+	res, err := db.execContext(ctx, updateStatement, params...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// SetField sets field to value in every item matching clause, returning the number of
+// items changed. value is encoded with the table's codec so it keeps its JSON type.
+func (n *Table[T]) SetField(ctx context.Context, clause Clause, field string, value any) (int64, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	clause = n.scoped(clause)
+	if err := errors.Join(validateFieldPath(field), clauseErr(clause)); err != nil {
+		return 0, err
+	}
+
+	b, err := n.codec.Marshal(value)
+	if err != nil {
+		return 0, err
+	}
+
+	value, args := n.expiringValue(fmt.Sprintf("json_set(data, '%s', json(?))", jsonPath(field)))
+	updateStatement := fmt.Sprintf("%s %s SET data = %s WHERE %s", "UPDATE", n.tableRef(), value, clause.Clause())
+	params := append(append([]any{string(b)}, args...), clause.Values()...)
+	res, err := n.store.execContext(ctx, updateStatement, params...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// RemoveField removes field from every item matching clause, returning the number of items changed
+func (n *Table[T]) RemoveField(ctx context.Context, clause Clause, field string) (int64, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	clause = n.scoped(clause)
+	if err := errors.Join(validateFieldPath(field), clauseErr(clause)); err != nil {
+		return 0, err
+	}
+
+	value, args := n.expiringValue(fmt.Sprintf("json_remove(data, '%s')", jsonPath(field)))
+	updateStatement := fmt.Sprintf("%s %s SET data = %s WHERE %s", "UPDATE", n.tableRef(), value, clause.Clause())
+	res, err := n.store.execContext(ctx, updateStatement, append(args, clause.Values()...)...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// UpdateMany replaces the item whose idField matches each key of items with its value,
+// within a single transaction, returning the number of items changed. Keys that match
+// no item are ignored.
+func (n *Table[T]) UpdateMany(ctx context.Context, idField string, items map[any]T) (int64, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	var total int64
+
+	err := n.store.WithTx(ctx, func(tx *Transaction) error {
+		for id, item := range items {
+			affected, err := n.update(ctx, tx, In(idField, id), item)
+			if err != nil {
+				return err
+			}
+			total += affected
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// UpsertByKeys replaces the item whose keyFields all equal those of data, or inserts data
+// if there is no such item. Returns an error if data is missing any of keyFields.
+func (n *Table[T]) UpsertByKeys(ctx context.Context, keyFields []string, data T) error {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	if len(keyFields) == 0 {
+		return errors.New("no key fields")
+	}
+
+	if err := n.validateDocument(data); err != nil {
+		return err
+	}
+
+	b, err := n.codec.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	clause, err := n.keyClause(ctx, keyFields, b)
+	if err != nil {
+		return err
+	}
+
+	// take the write lock up front so concurrent upserts of a new key cannot both insert
+	tx, err := n.store.BeginImmediate(ctx)
+	if err != nil {
+		return err
+	}
+
+	affected, err := n.update(ctx, tx, clause, data)
+	if err == nil && affected == 0 {
+		err = n.insert(ctx, tx, data)
+	}
+	if err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+	return tx.Commit()
+}
+
+// keyClause returns a clause matching the items whose keyFields equal those of the encoded
+// document b, returning an error if b is missing any of keyFields
+func (n *Table[T]) keyClause(ctx context.Context, keyFields []string, b []byte) (Clause, error) {
+	// extract the key values from the encoded document as they are extracted from stored items
+	extract := make([]string, len(keyFields))
+	args := make([]any, len(keyFields))
+	for i, field := range keyFields {
+		if err := validateFieldPath(field); err != nil {
+			return nil, err
+		}
+		extract[i] = jsonFieldOf("json(?)", field)
+		args[i] = string(b)
+	}
+
+	keys := make([]any, len(keyFields))
+	dest := make([]any, len(keyFields))
+	for i := range keys {
+		dest[i] = &keys[i]
+	}
+
+	err := n.store.queryRowContext(ctx, fmt.Sprintf("%s %s", "SELECT", strings.Join(extract, ", ")), args...).Scan(dest...)
+	if err != nil {
+		return nil, err
+	}
+	for i, key := range keys {
+		if key == nil {
+			return nil, fmt.Errorf("key field %q missing from document", keyFields[i])
+		}
+	}
+
+	return CompositeKey(keyFields, keys...), nil
+}
+
+// UpsertReturning replaces the item whose keyField equals that of data, or inserts data if
+// there is no such item, returning the document as stored. The stored document includes any
+// changes made while writing it, such as the expiry recorded by a view from WithTTL. If
+// several items share the key they are all replaced and the first is returned.
+func (n *Table[T]) UpsertReturning(ctx context.Context, keyField string, data T) (*T, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	if err := n.validateDocument(data); err != nil {
+		return nil, err
+	}
+
+	b, err := n.codec.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	clause, err := n.keyClause(ctx, []string{keyField}, b)
+	if err != nil {
+		return nil, err
+	}
+	clause = n.scoped(clause)
+	if err := clauseErr(clause); err != nil {
+		return nil, err
+	}
+
+	// take the write lock up front so concurrent upserts of a new key cannot both insert
+	tx, err := n.store.BeginImmediate(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []T
+	value, args := n.documentValue(b)
+	updateStatement := fmt.Sprintf("%s %s SET data = %s WHERE %s RETURNING data", "UPDATE", n.tableRef(), value, clause.Clause())
+	err = n.queryInto(ctx, tx, &results, updateStatement, append(args, clause.Values()...)...)
+	if err == nil && len(results) == 0 {
+		insertStatement := fmt.Sprintf("%s %s (data) VALUES (%s) RETURNING data", "INSERT INTO", n.tableRef(), value)
+		err = n.queryInto(ctx, tx, &results, insertStatement, args...)
+	}
+	if err == nil && len(results) == 0 {
+		err = errors.New("upsert returned no document")
+	}
+	if err != nil {
+		return nil, errors.Join(err, tx.Rollback())
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &results[0], nil
+}
+
+// maxUpsertManyChunk is the number of documents sent to SQLite in each UpsertMany statement
+const maxUpsertManyChunk = 500
+
+// UpsertMany replaces the item whose keyField matches each document in data, inserting
+// documents whose key is not yet stored, within a single transaction. Returns the number
+// of items changed or added. When data holds several documents with the same key the last
+// one wins. Documents without keyField are always inserted.
+func (n *Table[T]) UpsertMany(ctx context.Context, keyField string, data []T) (int64, error) {
+	ctx, cancel := n.operationContext(ctx)
+	defer cancel()
+
+	clause := n.scoped(All())
+	if err := errors.Join(validateFieldPath(keyField), clauseErr(clause)); err != nil {
+		return 0, err
+	}
+
+	key := jsonField(keyField)
+	valueKey := jsonFieldOf("j.value", keyField)
+	value, valueArgs := n.expiringValue("j.value")
+
+	// the last document in the chunk with a matching key replaces each stored item in scope
+	updateStatement := fmt.Sprintf("%s %s SET data = (SELECT %s FROM json_each(?) AS j WHERE %s = %s ORDER BY j.key DESC LIMIT 1) WHERE %s IN (SELECT %s FROM json_each(?) AS j) AND %s",
+		"UPDATE", n.tableRef(), value, valueKey, key, key, valueKey, clause.Clause())
+	// then the last document in the chunk for each key not yet stored in scope is inserted
+	insertStatement := fmt.Sprintf("%s %s (data) SELECT %s FROM json_each(?) AS j WHERE NOT EXISTS (SELECT 1 FROM json_each(?) AS k WHERE k.key > j.key AND %s = %s) AND NOT EXISTS (SELECT 1 FROM %s WHERE %s = %s AND %s)",
+		"INSERT INTO", n.tableRef(), value, jsonFieldOf("k.value", keyField), valueKey, n.tableRef(), key, valueKey, clause.Clause())
+
+	var total int64
+
+	err := n.store.WithTx(ctx, func(tx *Transaction) error {
+		for start := 0; start < len(data); start += maxUpsertManyChunk {
+			chunk := data[start:min(start+maxUpsertManyChunk, len(data))]
+
+			docs := make([][]byte, len(chunk))
+			for i, d := range chunk {
+				if err := n.validateDocument(d); err != nil {
+					return err
+				}
+				b, err := n.codec.Marshal(d)
+				if err != nil {
+					return err
+				}
+				docs[i] = b
+			}
+			batch := "[" + string(bytes.Join(docs, []byte(","))) + "]"
+
+			for _, statement := range []string{updateStatement, insertStatement} {
+				args := append(slices.Clone(valueArgs), batch, batch)
+				res, err := tx.execContext(ctx, statement, append(args, clause.Values()...)...)
+				if err != nil {
+					return err
+				}
+				affected, err := res.RowsAffected()
+				if err != nil {
+					return err
+				}
+				total += affected
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
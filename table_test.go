@@ -1,9 +1,17 @@
 package nosqlite
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"os"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	_ "github.com/glebarez/go-sqlite/compat"
 )
@@ -229,10 +237,94 @@ func TestTable_CreateIndex(t *testing.T) {
 
 	}
 
-	_, err = table.hasIndex(ctx, name)
+	found, err := table.hasIndex(ctx, name)
 	if err != nil {
 		t.Fatal(err)
 	}
+	if !found {
+		t.Errorf("expected index %s to exist", name)
+	}
+
+	found, err = table.hasIndex(ctx, "idx_missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found {
+		t.Errorf("expected idx_missing not to exist")
+	}
+}
+
+func TestTable_CreateIndexEquivalent(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	indexCount := func() int {
+		var count int
+		err := store.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND tbl_name = ?", table.Name).Scan(&count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+
+	first, err := table.CreateIndex(ctx, "$.name", "$.bar.name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := table.CreateIndex(ctx, " $.name", "$.bar.name ", "$.name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second != first {
+		t.Errorf("expected %s got %s", first, second)
+	}
+	if count := indexCount(); count != 1 {
+		t.Errorf("expected 1 index got %d", count)
+	}
+
+	// index names are case-insensitive but JSON keys are not
+	other, err := table.CreateIndex(ctx, "$.Name", "$.bar.name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.EqualFold(other, first) {
+		t.Errorf("expected a name distinct from %s got %s", first, other)
+	}
+	if count := indexCount(); count != 2 {
+		t.Errorf("expected 2 indexes got %d", count)
+	}
+}
+
+func TestTable_CreateIndexOrdered(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Event](ctx, t, store)
+
+	name, err := table.CreateIndexOrdered(ctx, Asc("$.name"), Desc("$.at"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "idx_nosqlite_event_name_at_desc" {
+		t.Errorf("expected idx_nosqlite_event_name_at_desc got %s", name)
+	}
+
+	plan := helperQueryPlan(ctx, t, store, fmt.Sprintf("SELECT data FROM %s ORDER BY data->>'$.name' ASC, data->>'$.at' DESC", table.Name))
+	if !strings.Contains(plan, name) || strings.Contains(plan, "TEMP B-TREE") {
+		t.Errorf("expected plan using %s without sorting got %s", name, plan)
+	}
+
+	_, err = table.CreateIndexOrdered(ctx, Desc("$.at").NullsLast())
+	if err == nil {
+		t.Error("expected error ordering nulls in an index got nil")
+	}
 }
 
 func TestTable_Count(t *testing.T) {
@@ -366,6 +458,14 @@ func TestTable_All(t *testing.T) {
 	if len(vals) != 2 {
 		t.Errorf("expected 2 got %d", len(vals))
 	}
+
+	vals, err = table.QueryMany(ctx, Or(All(), Equal("$.bar.name", "one")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 2 {
+		t.Errorf("expected 2 got %d", len(vals))
+	}
 }
 
 func TestTable_QueryOneInjectInValue(t *testing.T) {
@@ -419,8 +519,13 @@ func TestTable_QueryOneInjectInField(t *testing.T) {
 	}
 
 	_, err = table.QueryOne(ctx, Equal("$.name' OR 1=1 --", "injection"))
-	if err == nil {
-		t.Fatal("expected error got nil")
+	if !errors.Is(err, ErrInvalidField) {
+		t.Fatalf("expected %v got %v", ErrInvalidField, err)
+	}
+
+	_, err = table.QueryFirst(ctx, All(), Asc("$.name' OR 1=1 --"))
+	if !errors.Is(err, ErrInvalidField) {
+		t.Fatalf("expected %v got %v", ErrInvalidField, err)
 	}
 }
 
@@ -678,3 +783,3344 @@ func TestDeleteFromTables(t *testing.T) {
 		t.Fatalf("expected 0 got %d", len(tableTwoItems))
 	}
 }
+
+type Counter struct {
+	Name  string `json:"name,omitempty"`
+	Count int64  `json:"count,omitempty"`
+}
+
+func TestTable_QueryOneInt64(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Counter](ctx, t, store)
+
+	counters := []Counter{
+		{Name: "five", Count: 5},
+		{Name: "fifty", Count: 50},
+	}
+
+	for _, c := range counters {
+		err := table.Insert(ctx, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	val, err := table.QueryOne(ctx, Equal("$.count", int64(5)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val == nil || val.Name != "five" {
+		t.Errorf("expected five got %v", val)
+	}
+
+	vals, err := table.QueryMany(ctx, GreaterThan("$.count", uint64(6)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 1 || vals[0].Name != "fifty" {
+		t.Errorf("expected [fifty] got %v", vals)
+	}
+}
+
+func TestTable_QueryFirst(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	foos := []Foo{
+		{Id: 2, Name: "first", Bar: Bar{Name: "two"}},
+		{Id: 1, Name: "first", Bar: Bar{Name: "one"}},
+		{Id: 3, Name: "first", Bar: Bar{Name: "three"}},
+		{Id: 1, Name: "first", Bar: Bar{Name: "one-again"}},
+	}
+
+	for _, f := range foos {
+		err := table.Insert(ctx, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := Equal("$.name", "first")
+
+	val, err := table.QueryFirst(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val == nil || val.Bar.Name != "two" {
+		t.Errorf("expected two got %v", val)
+	}
+
+	val, err = table.QueryFirst(ctx, c, Asc("$.id"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val == nil || val.Bar.Name != "one" {
+		t.Errorf("expected one got %v", val)
+	}
+
+	val, err = table.QueryFirst(ctx, c, Desc("$.id"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val == nil || val.Bar.Name != "three" {
+		t.Errorf("expected three got %v", val)
+	}
+
+	val, err = table.QueryFirst(ctx, Equal("$.name", "missing"), Asc("$.id"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val != nil {
+		t.Fatal("expected nil result")
+	}
+}
+
+func TestTable_QueryManyStartsWith(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	for _, name := range []string{"50%_off", "50 percent off", "500_off", "half 50%_off"} {
+		err := table.Insert(ctx, Foo{Name: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vals, err := table.QueryMany(ctx, StartsWith("$.name", "50%_"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 1 || vals[0].Name != "50%_off" {
+		t.Errorf("expected [50%%_off] got %v", vals)
+	}
+
+	vals, err = table.QueryMany(ctx, EndsWith("$.name", "%_off"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 2 {
+		t.Errorf("expected 2 got %d", len(vals))
+	}
+}
+
+func TestTable_QueryManyStringContains(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	for _, name := range []string{"half 50%_off today", "50 percent off", "half 500_off today"} {
+		err := table.Insert(ctx, Foo{Name: name, List: []string{"50%_off"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vals, err := table.QueryMany(ctx, StringContains("$.name", "50%_off"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 1 || vals[0].Name != "half 50%_off today" {
+		t.Errorf("expected [half 50%%_off today] got %v", vals)
+	}
+}
+
+func TestTable_QueryManyNotLike(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	for _, name := range []string{"apple", "apricot", "banana", "cherry"} {
+		err := table.Insert(ctx, Foo{Name: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := func(vals []Foo) []string {
+		var names []string
+		for _, v := range vals {
+			names = append(names, v.Name)
+		}
+		return names
+	}
+
+	like, err := table.QueryMany(ctx, Like("$.name", "ap%"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	notLike, err := table.QueryMany(ctx, NotLike("$.name", "ap%"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := names(like); !slices.Equal(got, []string{"apple", "apricot"}) {
+		t.Errorf("expected [apple apricot] got %v", got)
+	}
+	if got := names(notLike); !slices.Equal(got, []string{"banana", "cherry"}) {
+		t.Errorf("expected [banana cherry] got %v", got)
+	}
+}
+
+func TestTable_QueryManyLikeEscape(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	for _, name := range []string{"50% off", "500 off"} {
+		err := table.Insert(ctx, Foo{Name: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vals, err := table.QueryMany(ctx, LikeEscape("$.name", "50!%%", '!'))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 1 || vals[0].Name != "50% off" {
+		t.Errorf("expected [50%% off] got %v", vals)
+	}
+}
+
+func TestTable_QueryField(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	err := table.Insert(ctx, Foo{Id: 1, Name: "field", Bar: Bar{Name: "nested"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	val, found, err := table.QueryField(ctx, Equal("$.id", 1), "$.name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found || val != "field" {
+		t.Errorf("expected field got %v (found %v)", val, found)
+	}
+
+	val, found, err = table.QueryField(ctx, Equal("$.id", 1), "$.bar.name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !found || val != "nested" {
+		t.Errorf("expected nested got %v (found %v)", val, found)
+	}
+
+	_, found, err = table.QueryField(ctx, Equal("$.id", 2), "$.name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found {
+		t.Error("expected not found")
+	}
+
+	_, _, err = table.QueryField(ctx, Equal("$.id", 1), "$.name'--")
+	if !errors.Is(err, ErrInvalidField) {
+		t.Errorf("expected ErrInvalidField got %v", err)
+	}
+}
+
+type Blob struct {
+	Name  string `json:"name,omitempty"`
+	Bytes []byte `json:"bytes,omitempty"`
+}
+
+func TestTable_QueryManyBytesEqual(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Blob](ctx, t, store)
+
+	for _, blob := range []Blob{{Name: "one", Bytes: []byte{0x00, 0xff, 0x10}}, {Name: "two", Bytes: []byte("two")}} {
+		err := table.Insert(ctx, blob)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	val, err := table.Get(ctx, BytesEqual("$.bytes", []byte{0x00, 0xff, 0x10}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val.Name != "one" || !bytes.Equal(val.Bytes, []byte{0x00, 0xff, 0x10}) {
+		t.Errorf("expected one with [0 255 16] got %v", val)
+	}
+}
+
+func TestTable_QueryManyJSONEqual(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Document](ctx, t, store)
+
+	docs := []Document{
+		{"id": "one", "bar": map[string]any{"name": "found"}, "list": []string{"a", "b"}, "flag": true},
+		{"id": "two", "bar": map[string]any{"name": "other"}, "list": []string{"b", "a"}, "flag": false},
+	}
+	for _, d := range docs {
+		err := table.Insert(ctx, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// ->> compares the comparand as given, so JSON that differs only in formatting misses
+	vals, err := table.QueryMany(ctx, Equal("$.bar", `{"name": "found"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 0 {
+		t.Errorf("expected no match got %v", vals)
+	}
+
+	tests := []struct {
+		name   string
+		clause Clause
+		want   string
+	}{
+		{"object", JSONEqual("$.bar", Bar{Name: "found"}), "one"},
+		{"array", JSONEqual("$.list", []string{"b", "a"}), "two"},
+		{"bool", JSONEqual("$.flag", true), "one"},
+		{"string", JSONEqual("$.id", "two"), "two"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vals, err := table.QueryMany(ctx, tt.clause)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(vals) != 1 || vals[0]["id"] != tt.want {
+				t.Errorf("expected [%s] got %v", tt.want, vals)
+			}
+		})
+	}
+}
+
+func TestTable_Get(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	err := table.Insert(ctx, Foo{Name: "get", Bar: Bar{Name: "found"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	val, err := table.Get(ctx, Equal("$.name", "get"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val.Bar.Name != "found" {
+		t.Errorf("expected found got %s", val.Bar.Name)
+	}
+
+	_, err = table.Get(ctx, Equal("$.name", "missing"))
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected %v got %v", ErrNotFound, err)
+	}
+}
+
+type Address struct {
+	City    string `json:"city,omitempty"`
+	Country string `json:"country,omitempty"`
+}
+
+type Person struct {
+	Name      string    `json:"name,omitempty"`
+	Addresses []Address `json:"addresses,omitempty"`
+}
+
+type Scored struct {
+	Name   string    `json:"name,omitempty"`
+	Scores []int     `json:"scores,omitempty"`
+	Ratios []float32 `json:"ratios,omitempty"`
+	Big    []uint64  `json:"big,omitempty"`
+}
+
+func TestTable_QueryManyContainsNumbers(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Scored](ctx, t, store)
+
+	items := []Scored{
+		{Name: "one", Scores: []int{1, 2, 3}, Ratios: []float32{0.1, 0.5}, Big: []uint64{math.MaxUint64}},
+		{Name: "two", Scores: []int{3, 4, 5}, Ratios: []float32{0.5}},
+		{Name: "three", Scores: []int{-1}},
+	}
+	for _, item := range items {
+		err := table.Insert(ctx, item)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := func(vals []Scored) []string {
+		var names []string
+		for _, v := range vals {
+			names = append(names, v.Name)
+		}
+		return names
+	}
+
+	tests := []struct {
+		name   string
+		clause Clause
+		want   []string
+	}{
+		{"int", Contains("$.scores", 3), []string{"one", "two"}},
+		{"int8", Contains("$.scores", int8(-1)), []string{"three"}},
+		{"uint16", Contains("$.scores", uint16(5)), []string{"two"}},
+		{"int64 all", ContainsAll("$.scores", int64(1), int64(3)), []string{"one"}},
+		{"int any", ContainsAny("$.scores", 2, 4), []string{"one", "two"}},
+		{"float64 whole", Contains("$.scores", 4.0), []string{"two"}},
+		{"float32", Contains("$.ratios", float32(0.1)), []string{"one"}},
+		{"float32 any", ContainsAny("$.ratios", float32(0.5)), []string{"one", "two"}},
+		{"uint64 max", Contains("$.big", uint64(math.MaxUint64)), []string{"one"}},
+		{"not contains int", NotContainsAny("$.scores", 1, 5), []string{"three"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vals, err := table.QueryMany(ctx, tt.clause)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := names(vals); !slices.Equal(got, tt.want) {
+				t.Errorf("got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTable_QueryManyContainsWhere(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Person](ctx, t, store)
+
+	people := []Person{
+		{Name: "one", Addresses: []Address{{City: "London", Country: "uk"}, {City: "Paris", Country: "fr"}}},
+		{Name: "two", Addresses: []Address{{City: "Leeds", Country: "uk"}}},
+		{Name: "three", Addresses: []Address{{City: "Lyon", Country: "fr"}}},
+		{Name: "four"},
+	}
+
+	for _, p := range people {
+		err := table.Insert(ctx, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vals, err := table.QueryMany(ctx, ContainsWhere("$.addresses", Equal("$.city", "London")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 1 || vals[0].Name != "one" {
+		t.Errorf("expected [one] got %v", vals)
+	}
+
+	vals, err = table.QueryMany(ctx, ContainsWhere("$.addresses", Equal("$.country", "fr")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 2 {
+		t.Errorf("expected 2 got %d", len(vals))
+	}
+
+	vals, err = table.QueryMany(ctx, ContainsWhere("$.addresses", And(Equal("$.country", "uk"), StartsWith("$.city", "Le"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 1 || vals[0].Name != "two" {
+		t.Errorf("expected [two] got %v", vals)
+	}
+}
+
+func TestDeleteByIDs(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	total := 2500
+	err := store.WithTx(ctx, func(tx *Transaction) error {
+		txTable := table.WithTransaction(tx)
+		for i := 1; i <= total; i++ {
+			err := txTable.Insert(ctx, Foo{Id: i})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := make([]int, 0, total)
+	for i := 1; i <= total-10; i++ {
+		ids = append(ids, i)
+	}
+
+	deleted, err := DeleteByIDs(ctx, table, "$.id", ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != int64(len(ids)) {
+		t.Errorf("expected %d got %d", len(ids), deleted)
+	}
+
+	count, err := table.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 10 {
+		t.Errorf("expected 10 got %d", count)
+	}
+}
+
+func TestTable_ReplaceAll(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	oldFoos := make([]Foo, 50)
+	for i := range oldFoos {
+		oldFoos[i] = Foo{Id: i + 1, Name: "old"}
+	}
+
+	newFoos := make([]Foo, 200)
+	for i := range newFoos {
+		newFoos[i] = Foo{Id: i + 1, Name: "new"}
+	}
+
+	err := table.ReplaceAll(ctx, oldFoos)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	readErrs := make(chan error, 1)
+	go func() {
+		defer close(readErrs)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			count, err := table.Count(ctx)
+			if err != nil {
+				readErrs <- err
+				return
+			}
+			if count != uint64(len(oldFoos)) && count != uint64(len(newFoos)) {
+				readErrs <- fmt.Errorf("read partial state of %d items", count)
+				return
+			}
+		}
+	}()
+
+	err = table.ReplaceAll(ctx, newFoos)
+	close(done)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for err := range readErrs {
+		t.Error(err)
+	}
+
+	vals, err := table.QueryMany(ctx, Equal("$.name", "new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != len(newFoos) {
+		t.Errorf("expected %d got %d", len(newFoos), len(vals))
+	}
+}
+
+func TestTable_StorageSize(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	foos := []Foo{
+		{Id: 1, Name: "size-one", List: []string{"a", "b"}},
+		{Id: 2, Name: "size-two", Bar: Bar{Name: "bär"}},
+		{Id: 3, Name: "other"},
+	}
+
+	var expected int64
+	for _, f := range foos {
+		err := table.Insert(ctx, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if f.Name != "other" {
+			b, err := json.Marshal(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected += int64(len(b))
+		}
+	}
+
+	size, err := table.StorageSize(ctx, StartsWith("$.name", "size-"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != expected {
+		t.Errorf("expected %d got %d", expected, size)
+	}
+
+	size, err = table.StorageSize(ctx, Equal("$.name", "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size != 0 {
+		t.Errorf("expected 0 got %d", size)
+	}
+}
+
+type Document map[string]any
+
+func TestTable_QueryManyArrayEmpty(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Document](ctx, t, store)
+
+	docs := []Document{
+		{"id": "absent"},
+		{"id": "null", "tags": nil},
+		{"id": "empty", "tags": []any{}},
+		{"id": "populated", "tags": []any{"a", "b"}},
+	}
+
+	for _, d := range docs {
+		err := table.Insert(ctx, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		clause Clause
+		want   []any
+	}{
+		{ArrayEmpty("$.tags"), []any{"absent", "null", "empty"}},
+		{ArrayNotEmpty("$.tags"), []any{"populated"}},
+	}
+	for _, tt := range tests {
+		vals, err := table.QueryMany(ctx, tt.clause)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := make([]any, len(vals))
+		for i, v := range vals {
+			ids[i] = v["id"]
+		}
+		if !slices.Equal(ids, tt.want) {
+			t.Errorf("got = %v, want %v", ids, tt.want)
+		}
+	}
+}
+
+func TestTable_QueryManyHasKey(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Document](ctx, t, store)
+
+	docs := []Document{
+		{"id": "absent"},
+		{"id": "null", "key": nil},
+		{"id": "value", "key": "set"},
+	}
+
+	for _, d := range docs {
+		err := table.Insert(ctx, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vals, err := table.QueryMany(ctx, HasKey("$.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 2 {
+		t.Fatalf("expected 2 got %d", len(vals))
+	}
+
+	ids := []any{vals[0]["id"], vals[1]["id"]}
+	if !slices.Contains(ids, "null") || !slices.Contains(ids, "value") {
+		t.Errorf("expected null and value got %v", ids)
+	}
+}
+
+func TestTable_QueryManyIsType(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Document](ctx, t, store)
+
+	docs := []Document{
+		{"id": "null", "value": nil},
+		{"id": "true", "value": true},
+		{"id": "false", "value": false},
+		{"id": "integer", "value": 1},
+		{"id": "real", "value": 1.5},
+		{"id": "text", "value": "one"},
+		{"id": "array", "value": []any{1, 2}},
+		{"id": "object", "value": map[string]any{"one": 1}},
+		{"id": "absent"},
+	}
+
+	for _, d := range docs {
+		err := table.Insert(ctx, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	types := []JSONType{JSONNull, JSONTrue, JSONFalse, JSONInteger, JSONReal, JSONString, JSONArray, JSONObject}
+	for _, jsonType := range types {
+		vals, err := table.QueryMany(ctx, IsType("$.value", jsonType))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(vals) != 1 || vals[0]["id"] != string(jsonType) {
+			t.Errorf("expected [%s] got %v", jsonType, vals)
+		}
+	}
+}
+
+func TestTable_UpdateMany(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	for i := 1; i <= 3; i++ {
+		err := table.Insert(ctx, Foo{Id: i, Name: "before"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	items := map[any]Foo{
+		1:  {Id: 1, Name: "after-one"},
+		3:  {Id: 3, Name: "after-three"},
+		42: {Id: 42, Name: "missing"},
+	}
+
+	affected, err := table.UpdateMany(ctx, "$.id", items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if affected != 2 {
+		t.Errorf("expected 2 got %d", affected)
+	}
+
+	for id, name := range map[int]string{1: "after-one", 2: "before", 3: "after-three"} {
+		val, err := table.Get(ctx, Equal("$.id", id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if val.Name != name {
+			t.Errorf("expected %s got %s", name, val.Name)
+		}
+	}
+
+	count, err := table.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 got %d", count)
+	}
+}
+
+func TestTable_WithTimeout(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	err := store.WithTx(ctx, func(tx *Transaction) error {
+		txTable := table.WithTransaction(tx)
+		for i := 1; i <= 1000; i++ {
+			err := txTable.Insert(ctx, Foo{Id: i, Name: "timeout"})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = table.WithTimeout(time.Nanosecond).QueryMany(ctx, Equal("$.name", "timeout"))
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected %v got %v", context.DeadlineExceeded, err)
+	}
+
+	vals, err := table.WithTimeout(time.Minute).QueryMany(ctx, Equal("$.name", "timeout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 1000 {
+		t.Errorf("expected 1000 got %d", len(vals))
+	}
+}
+
+func TestTable_DistinctCount(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	foos := []Foo{
+		{Id: 1, Name: "alice", Bar: Bar{Name: "login"}},
+		{Id: 2, Name: "bob", Bar: Bar{Name: "login"}},
+		{Id: 3, Name: "alice", Bar: Bar{Name: "login"}},
+		{Id: 4, Name: "alice", Bar: Bar{Name: "logout"}},
+		{Id: 5, Name: "carol", Bar: Bar{Name: "logout"}},
+	}
+
+	for _, f := range foos {
+		err := table.Insert(ctx, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count, err := table.DistinctCount(ctx, "$.name", All())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 got %d", count)
+	}
+
+	count, err = table.DistinctCount(ctx, "$.name", Equal("$.bar.name", "login"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 got %d", count)
+	}
+
+	_, err = table.DistinctCount(ctx, "$.name' --", All())
+	if !errors.Is(err, ErrInvalidField) {
+		t.Errorf("expected %v got %v", ErrInvalidField, err)
+	}
+}
+
+func TestTable_QueryOneDeeplyNested(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Document](ctx, t, store)
+
+	docs := []Document{
+		{"id": "deep", "a": map[string]any{"b": map[string]any{"c": map[string]any{"d": "found"}}}},
+		{"id": "shallow", "a": map[string]any{"b": "found"}},
+		{"id": "literal", "a.b.c.d": "found"},
+	}
+
+	for _, d := range docs {
+		err := table.Insert(ctx, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, field := range []string{"$.a.b.c.d", "a.b.c.d"} {
+		vals, err := table.QueryMany(ctx, Equal(field, "found"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(vals) != 1 || vals[0]["id"] != "deep" {
+			t.Errorf("expected [deep] for %s got %v", field, vals)
+		}
+	}
+
+	vals, err := table.QueryMany(ctx, Equal("$.a.b", "found"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 1 || vals[0]["id"] != "shallow" {
+		t.Errorf("expected [shallow] got %v", vals)
+	}
+}
+
+func TestTable_SetField(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Document](ctx, t, store)
+
+	docs := []Document{
+		{"id": "one", "group": "a"},
+		{"id": "two", "group": "a"},
+		{"id": "three", "group": "b"},
+	}
+
+	for _, d := range docs {
+		err := table.Insert(ctx, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		field    string
+		value    any
+		jsonType JSONType
+	}{
+		{"$.status", "active", JSONString},
+		{"$.count", 5, JSONInteger},
+		{"$.enabled", true, JSONTrue},
+		{"$.nested.flag", false, JSONFalse},
+	}
+
+	for _, test := range tests {
+		if test.field == "$.nested.flag" {
+			_, err := table.SetField(ctx, Equal("$.group", "a"), "$.nested", map[string]any{})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		affected, err := table.SetField(ctx, Equal("$.group", "a"), test.field, test.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if affected != 2 {
+			t.Errorf("expected 2 got %d", affected)
+		}
+
+		vals, err := table.QueryMany(ctx, IsType(test.field, test.jsonType))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(vals) != 2 {
+			t.Errorf("expected 2 %s values for %s got %d", test.jsonType, test.field, len(vals))
+		}
+	}
+
+	val, err := table.Get(ctx, Equal("$.id", "one"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val["status"] != "active" || val["count"] != float64(5) || val["enabled"] != true {
+		t.Errorf("unexpected document %v", val)
+	}
+
+	val, err = table.Get(ctx, Equal("$.id", "three"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := val["status"]; ok {
+		t.Errorf("expected status to be unset got %v", val)
+	}
+}
+
+func TestTable_RemoveField(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Document](ctx, t, store)
+
+	docs := []Document{
+		{"id": "one", "group": "a", "secret": "x"},
+		{"id": "two", "group": "a", "secret": nil},
+		{"id": "three", "group": "b", "secret": "y"},
+	}
+
+	for _, d := range docs {
+		err := table.Insert(ctx, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	affected, err := table.RemoveField(ctx, Equal("$.group", "a"), "$.secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if affected != 2 {
+		t.Errorf("expected 2 got %d", affected)
+	}
+
+	vals, err := table.QueryMany(ctx, HasKey("$.secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 1 || vals[0]["id"] != "three" {
+		t.Errorf("expected [three] got %v", vals)
+	}
+
+	vals, err = table.QueryMany(ctx, IsType("$.secret", JSONNull))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 0 {
+		t.Errorf("expected 0 got %d", len(vals))
+	}
+}
+
+func TestTable_QueryFirstNulls(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Document](ctx, t, store)
+
+	docs := []Document{
+		{"id": "missing"},
+		{"id": "early", "ts": 100},
+		{"id": "null", "ts": nil},
+		{"id": "late", "ts": 300},
+	}
+
+	for _, d := range docs {
+		err := table.Insert(ctx, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		order    Order
+		expected string
+	}{
+		{Asc("$.ts"), "missing"},
+		{Asc("$.ts").NullsLast(), "early"},
+		{Desc("$.ts"), "late"},
+		{Desc("$.ts").NullsFirst(), "missing"},
+	}
+
+	for _, test := range tests {
+		val, err := table.QueryFirst(ctx, All(), test.order)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if val == nil || (*val)["id"] != test.expected {
+			t.Errorf("expected %s for %s got %v", test.expected, test.order.term(), val)
+		}
+	}
+}
+
+func helperQueryPlan(ctx context.Context, t *testing.T, store *Store, query string) string {
+	t.Helper()
+
+	rows, err := store.db.QueryContext(ctx, "EXPLAIN QUERY PLAN "+query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var details []string
+	for rows.Next() {
+		var id, parent, notUsed int
+		var detail string
+		err := rows.Scan(&id, &parent, &notUsed, &detail)
+		if err != nil {
+			t.Fatal(err)
+		}
+		details = append(details, detail)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return strings.Join(details, "\n")
+}
+
+func TestTable_CreatePartialIndex(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Document](ctx, t, store)
+
+	for i := 0; i < 20; i++ {
+		status := "inactive"
+		if i%10 == 0 {
+			status = "active"
+		}
+		err := table.Insert(ctx, Document{"name": fmt.Sprintf("name-%d", i), "status": status})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	active := Equal("$.status", "active")
+
+	name, err := table.CreatePartialIndex(ctx, []string{"$.name"}, active)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sqlText string
+	err = store.db.QueryRowContext(ctx, "SELECT sql FROM sqlite_master WHERE type='index' AND name=?", name).Scan(&sqlText)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(sqlText, "WHERE (data->>'$.status' = 'active')") {
+		t.Errorf("expected partial index predicate got %s", sqlText)
+	}
+
+	query := fmt.Sprintf("SELECT data FROM `%s` WHERE %s", table.Name, Debug(And(active, Equal("$.name", "name-10"))))
+	if plan := helperQueryPlan(ctx, t, store, query); !strings.Contains(plan, name) {
+		t.Errorf("expected query plan to use %s got %s", name, plan)
+	}
+
+	_, err = table.CreatePartialIndex(ctx, []string{"$.name"}, Equal("$.status' --", "active"))
+	if !errors.Is(err, ErrInvalidField) {
+		t.Errorf("expected %v got %v", ErrInvalidField, err)
+	}
+}
+
+func TestTable_CollectField(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Document](ctx, t, store)
+
+	docs := []Document{
+		{"email": "a@example.com", "status": "active", "n": 1},
+		{"email": "b@example.com", "status": "inactive", "n": 2},
+		{"email": "c@example.com", "status": "active", "n": 3},
+		{"status": "active", "tags": []any{"x"}},
+	}
+	for _, doc := range docs {
+		err := table.Insert(ctx, doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		field  string
+		clause Clause
+		want   string
+	}{
+		{"$.email", Equal("$.status", "active"), `["a@example.com","c@example.com",null]`},
+		{"tags", Is("$.email", nil), `[["x"]]`},
+		{"$.n", GreaterThan("$.n", 1), `[2,3]`},
+		{"$.email", Equal("$.email", "missing"), `[]`},
+	}
+	for _, tt := range tests {
+		values, err := table.CollectField(ctx, tt.field, tt.clause)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if values == nil {
+			t.Errorf("expected non-nil values for %s", tt.field)
+		}
+		got, err := json.Marshal(values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("got = %s, want %s", got, tt.want)
+		}
+	}
+
+	_, err := table.CollectField(ctx, "$.email'", All())
+	if !errors.Is(err, ErrInvalidField) {
+		t.Errorf("expected %v got %v", ErrInvalidField, err)
+	}
+}
+
+func TestTable_RebuildIndex(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Document](ctx, t, store)
+
+	for i := 0; i < 20; i++ {
+		status := "inactive"
+		if i%10 == 0 {
+			status = "active"
+		}
+		err := table.Insert(ctx, Document{"name": fmt.Sprintf("name-%d", i), "status": status})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	active := Equal("$.status", "active")
+
+	partialName, err := table.CreatePartialIndex(ctx, []string{"$.name"}, active)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, err := table.CreateIndex(ctx, "$.name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = table.RebuildIndex(ctx, "$.name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, indexName := range []string{name, partialName} {
+		var sqlText string
+		err = store.db.QueryRowContext(ctx, "SELECT sql FROM sqlite_master WHERE type='index' AND name=?", indexName).Scan(&sqlText)
+		if err != nil {
+			t.Fatalf("expected index %s to exist got %v", indexName, err)
+		}
+	}
+
+	query := fmt.Sprintf("SELECT data FROM `%s` WHERE %s", table.Name, Debug(Equal("$.name", "name-10")))
+	if plan := helperQueryPlan(ctx, t, store, query); !strings.Contains(plan, name) {
+		t.Errorf("expected query plan to use %s got %s", name, plan)
+	}
+
+	err = table.RebuildIndex(ctx, "$.status")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected %v got %v", ErrNotFound, err)
+	}
+
+	// indexes named other than by the fields alone are found by what they index
+	ordered, err := table.CreateIndexOrdered(ctx, Asc("$.status"), Desc("$.name"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collided, err := table.CreateIndex(ctx, "$.Name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fields := range [][]string{{"$.status", "$.name"}, {" $.Name "}} {
+		err = table.RebuildIndex(ctx, fields...)
+		if err != nil {
+			t.Fatalf("rebuilding index on %v: %v", fields, err)
+		}
+	}
+	for _, indexName := range []string{ordered, collided} {
+		var sqlText string
+		err = store.db.QueryRowContext(ctx, "SELECT sql FROM sqlite_master WHERE type='index' AND name=?", indexName).Scan(&sqlText)
+		if err != nil {
+			t.Fatalf("expected index %s to exist got %v", indexName, err)
+		}
+	}
+
+	// an index on more fields than given is not rebuilt
+	err = table.RebuildIndex(ctx, "$.status")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected %v got %v", ErrNotFound, err)
+	}
+}
+
+func TestTable_AddGeneratedColumn(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Counter](ctx, t, store)
+
+	for i := int64(1); i <= 20; i++ {
+		err := table.Insert(ctx, Counter{Name: fmt.Sprintf("counter-%d", i), Count: i})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := table.AddGeneratedColumn(ctx, "count_value", "$.count", "integer")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name, err := table.CreateColumnIndex(ctx, "count_value")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var c int64
+	err = store.db.QueryRowContext(ctx, fmt.Sprintf("SELECT count_value FROM `%s` WHERE data->>'$.name' = ?", table.Name), "counter-7").Scan(&c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != 7 {
+		t.Errorf("expected 7 got %d", c)
+	}
+
+	query := fmt.Sprintf("SELECT data FROM `%s` WHERE count_value > 15", table.Name)
+	if plan := helperQueryPlan(ctx, t, store, query); !strings.Contains(plan, name) {
+		t.Errorf("expected query plan to use %s got %s", name, plan)
+	}
+
+	// existing operations are unaffected by the extra column
+	val, err := table.Get(ctx, Equal("$.count", 7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val.Name != "counter-7" {
+		t.Errorf("expected counter-7 got %s", val.Name)
+	}
+
+	err = table.AddGeneratedColumn(ctx, "other", "$.count", "VARCHAR")
+	if err == nil {
+		t.Fatal("expected error got nil")
+	}
+}
+
+func TestTable_QueryManyNumericStrings(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Document](ctx, t, store)
+
+	for _, n := range []string{"9", "10", "100", "2.5"} {
+		err := table.Insert(ctx, Document{"n": n})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// lexicographically "10" and "100" sort before "9"
+	vals, err := table.QueryMany(ctx, GreaterThan("$.n", "9"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 0 {
+		t.Errorf("expected 0 got %d", len(vals))
+	}
+
+	vals, err = table.QueryMany(ctx, NumericGreaterThan("$.n", 9))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 2 {
+		t.Errorf("expected 2 got %d", len(vals))
+	}
+
+	vals, err = table.QueryMany(ctx, NumericLessThanOrEqual("$.n", 9))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 2 {
+		t.Errorf("expected 2 got %d", len(vals))
+	}
+
+	val, err := table.Get(ctx, NumericEqual("$.n", 2.5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val["n"] != "2.5" {
+		t.Errorf("expected 2.5 got %v", val["n"])
+	}
+}
+
+func TestTable_Reindex(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	_, err := table.CreateIndex(ctx, "$.name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.WithTx(ctx, func(tx *Transaction) error {
+		txTable := table.WithTransaction(tx)
+		for i := 1; i <= 1000; i++ {
+			err := txTable.Insert(ctx, Foo{Id: i, Name: fmt.Sprintf("name-%d", i)})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = table.Reindex(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.Reindex(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	val, err := table.Get(ctx, Equal("$.name", "name-500"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val.Id != 500 {
+		t.Errorf("expected 500 got %d", val.Id)
+	}
+}
+
+func TestTable_UpsertMany(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	for i := 1; i <= 2; i++ {
+		err := table.Insert(ctx, Foo{Id: i, Name: "before"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data := []Foo{
+		{Id: 2, Name: "updated"},
+		{Id: 3, Name: "first"},
+		{Id: 4, Name: "inserted"},
+		{Id: 3, Name: "last"},
+	}
+
+	affected, err := table.UpsertMany(ctx, "id", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if affected != 3 {
+		t.Errorf("expected 3 got %d", affected)
+	}
+
+	for id, name := range map[int]string{1: "before", 2: "updated", 3: "last", 4: "inserted"} {
+		val, err := table.Get(ctx, Equal("$.id", id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if val.Name != name {
+			t.Errorf("expected %s got %s", name, val.Name)
+		}
+	}
+
+	count, err := table.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 4 {
+		t.Errorf("expected 4 got %d", count)
+	}
+
+	_, err = table.UpsertMany(ctx, "id'", data)
+	if !errors.Is(err, ErrInvalidField) {
+		t.Errorf("expected %v got %v", ErrInvalidField, err)
+	}
+}
+
+func TestTable_UpsertManyScoped(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[TenantItem](ctx, t, store)
+
+	err := table.Insert(ctx, TenantItem{Tenant: "a", Id: 1, Name: "a-original"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scoped := table.Scoped(Equal("$.tenant", "b"))
+	affected, err := scoped.UpsertMany(ctx, "$.id", []TenantItem{{Tenant: "b", Id: 1, Name: "b-inserted"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if affected != 1 {
+		t.Errorf("expected 1 got %d", affected)
+	}
+
+	other, err := table.Get(ctx, Equal("$.tenant", "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.Name != "a-original" {
+		t.Errorf("expected a-original got %s", other.Name)
+	}
+
+	_, err = scoped.UpsertMany(ctx, "$.id", []TenantItem{{Tenant: "b", Id: 1, Name: "b-updated"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vals, err := table.QueryMany(ctx, Equal("$.id", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, len(vals))
+	for i, v := range vals {
+		names[i] = v.Name
+	}
+	if want := []string{"a-original", "b-updated"}; !slices.Equal(names, want) {
+		t.Errorf("got = %v, want %v", names, want)
+	}
+}
+
+func TestTable_Validate(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	err := table.Insert(ctx, Foo{Id: 1, Name: "valid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := store.db.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (data) VALUES (?)", table.tableRef()), `{"id": 2, "name": "trunc`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupt, err := res.LastInsertId()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = table.Insert(ctx, Foo{Id: 3, Name: "valid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invalid, err := table.Validate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(invalid) != 1 || invalid[0] != corrupt {
+		t.Errorf("expected [%d] got %v", corrupt, invalid)
+	}
+
+	// items outside the scope of a view are not reported
+	_, err = store.db.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (data) VALUES (?)", table.tableRef()), `{"id": "four", "name": "scoped"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	invalid, err = table.Scoped(Equal("$.name", "valid")).Validate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(invalid) != 0 {
+		t.Errorf("expected no invalid items got %v", invalid)
+	}
+
+	invalid, err = table.Scoped(Equal("$.name", "scoped")).Validate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(invalid) != 1 {
+		t.Errorf("expected 1 invalid item got %v", invalid)
+	}
+}
+
+func TestTable_QueryManyInto(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	for i := 1; i <= 3; i++ {
+		err := table.Insert(ctx, Foo{Id: i, Name: fmt.Sprintf("name-%d", i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dst := make([]Foo, 0, 8)
+	dst = append(dst, Foo{Id: 42})
+
+	err := table.QueryManyInto(ctx, GreaterThan("$.id", 1), &dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dst) != 2 || dst[0].Id != 2 || dst[1].Id != 3 {
+		t.Errorf("expected ids [2 3] got %v", dst)
+	}
+	if cap(dst) != 8 {
+		t.Errorf("expected capacity 8 got %d", cap(dst))
+	}
+
+	err = table.QueryManyInto(ctx, Equal("$.id", 42), &dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dst) != 0 {
+		t.Errorf("expected 0 got %d", len(dst))
+	}
+}
+
+func benchmarkQueryMany(b *testing.B, query func(ctx context.Context, table *Table[Foo]) error) {
+	ctx := context.Background()
+
+	f, err := os.CreateTemp(os.TempDir(), "bench-nosqlite.db")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	store, err := NewStore(f.Name())
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() { _ = store.Close() }()
+
+	table, err := NewTable[Foo](ctx, store)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for i := 1; i <= 100; i++ {
+		err := table.Insert(ctx, Foo{Id: i, Name: "bench"})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		err := query(ctx, table)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTable_QueryMany(b *testing.B) {
+	benchmarkQueryMany(b, func(ctx context.Context, table *Table[Foo]) error {
+		_, err := table.QueryMany(ctx, All())
+		return err
+	})
+}
+
+func BenchmarkTable_QueryManyInto(b *testing.B) {
+	var dst []Foo
+	benchmarkQueryMany(b, func(ctx context.Context, table *Table[Foo]) error {
+		return table.QueryManyInto(ctx, All(), &dst)
+	})
+}
+
+type Event struct {
+	Id int       `json:"id,omitempty"`
+	At time.Time `json:"at"`
+}
+
+func TestTable_QueryManyAfterBefore(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Event](ctx, t, store)
+
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	events := []Event{
+		{Id: 1, At: start.Add(-time.Hour)},
+		// fractional seconds and a non-UTC offset do not compare correctly as strings
+		{Id: 2, At: start.Add(500 * time.Millisecond).In(time.FixedZone("", -5*3600))},
+		{Id: 3, At: start.Add(30 * time.Minute)},
+		{Id: 4, At: start.Add(2 * time.Hour)},
+	}
+	for _, e := range events {
+		err := table.Insert(ctx, e)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vals, err := table.QueryMany(ctx, And(After("$.at", start), Before("$.at", start.Add(time.Hour))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 2 || vals[0].Id != 2 || vals[1].Id != 3 {
+		t.Errorf("expected ids [2 3] got %v", vals)
+	}
+}
+
+func TestTable_QueryManyBetweenTime(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Event](ctx, t, store)
+
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	events := []Event{
+		{Id: 1, At: start.Add(-time.Millisecond)},
+		{Id: 2, At: start.In(time.FixedZone("", -5*3600))},
+		{Id: 3, At: start.Add(30 * time.Minute)},
+		{Id: 4, At: start.Add(time.Hour)},
+		{Id: 5, At: start.Add(time.Hour + time.Millisecond)},
+	}
+	for _, e := range events {
+		err := table.Insert(ctx, e)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vals, err := table.QueryMany(ctx, BetweenTime("$.at", start, start.Add(time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]int, len(vals))
+	for i, v := range vals {
+		ids[i] = v.Id
+	}
+	if want := []int{2, 3, 4}; !slices.Equal(ids, want) {
+		t.Errorf("got = %v, want %v", ids, want)
+	}
+}
+
+func TestTable_ExplainQueryPlan(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	indexName, err := table.CreateIndex(ctx, "$.name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := table.ExplainQueryPlan(ctx, Equal("$.name", "indexed"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(strings.Join(plan, "\n"), indexName) {
+		t.Errorf("expected plan to use %s got %v", indexName, plan)
+	}
+
+	plan, err = table.ExplainQueryPlan(ctx, Equal("$.id", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(strings.Join(plan, "\n"), indexName) {
+		t.Errorf("expected plan not to use %s got %v", indexName, plan)
+	}
+}
+
+func TestTable_Rename(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	for i := 1; i <= 3; i++ {
+		err := table.Insert(ctx, Foo{Id: i, Name: fmt.Sprintf("name-%d", i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := table.CreateIndex(ctx, "$.name")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = table.Rename(ctx, "renamed_foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Name != "renamed_foo" {
+		t.Errorf("expected renamed_foo got %s", table.Name)
+	}
+
+	tables, err := store.Tables(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 1 || tables[0] != "renamed_foo" {
+		t.Errorf("expected [renamed_foo] got %v", tables)
+	}
+
+	val, err := table.Get(ctx, Equal("$.name", "name-2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val.Id != 2 {
+		t.Errorf("expected 2 got %d", val.Id)
+	}
+
+	var indexes []string
+	rows, err := store.db.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = 'renamed_foo'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		indexes = append(indexes, name)
+	}
+	if len(indexes) != 1 || indexes[0] != "idx_renamed_foo_name" {
+		t.Errorf("expected [idx_renamed_foo_name] got %v", indexes)
+	}
+
+	for _, name := range []string{"", "bad name", "bad`name", "sqlite_foo"} {
+		if err := table.Rename(ctx, name); !errors.Is(err, ErrInvalidTableName) {
+			t.Errorf("expected %v for %q got %v", ErrInvalidTableName, name, err)
+		}
+	}
+}
+
+type TenantItem struct {
+	Tenant string `json:"tenant,omitempty"`
+	Id     int    `json:"id,omitempty"`
+	Name   string `json:"name,omitempty"`
+}
+
+func TestTable_UpsertByKeys(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[TenantItem](ctx, t, store)
+
+	keys := []string{"tenant", "id"}
+	items := []TenantItem{
+		{Tenant: "a", Id: 1, Name: "first"},
+		{Tenant: "b", Id: 1, Name: "other tenant"},
+		{Tenant: "a", Id: 2, Name: "other id"},
+		{Tenant: "a", Id: 1, Name: "second"},
+	}
+	for _, item := range items {
+		err := table.UpsertByKeys(ctx, keys, item)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count, err := table.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 got %d", count)
+	}
+
+	val, err := table.Get(ctx, CompositeKey(keys, "a", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val.Name != "second" {
+		t.Errorf("expected second got %s", val.Name)
+	}
+
+	err = table.UpsertByKeys(ctx, keys, TenantItem{Id: 3})
+	if err == nil {
+		t.Error("expected error for missing key field got nil")
+	}
+}
+
+func TestTable_CreateIndexesTx(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	indexCount := func() int {
+		var c int
+		err := store.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND tbl_name = ?", table.Name).Scan(&c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	// an index without fields is rejected by SQLite after the first index is created
+	_, err := table.CreateIndexesTx(ctx, []string{"$.name"}, []string{})
+	if err == nil {
+		t.Fatal("expected error got nil")
+	}
+	if c := indexCount(); c != 0 {
+		t.Errorf("expected 0 indexes got %d", c)
+	}
+
+	names, err := table.CreateIndexesTx(ctx, []string{"$.name"}, []string{"$.bar.name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "idx_nosqlite_foo_name" || names[1] != "idx_nosqlite_foo_bar__name" {
+		t.Errorf("expected [idx_nosqlite_foo_name idx_nosqlite_foo_bar__name] got %v", names)
+	}
+	if c := indexCount(); c != 2 {
+		t.Errorf("expected 2 indexes got %d", c)
+	}
+}
+
+func TestTable_DeleteLimit(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	for i := 1; i <= 10; i++ {
+		name := "expired"
+		if i > 7 {
+			name = "live"
+		}
+		err := table.Insert(ctx, Foo{Id: i, Name: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var batches []int64
+	for {
+		deleted, err := table.DeleteLimit(ctx, Equal("$.name", "expired"), 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if deleted == 0 {
+			break
+		}
+		batches = append(batches, deleted)
+	}
+
+	if !slices.Equal(batches, []int64{3, 3, 1}) {
+		t.Errorf("expected [3 3 1] got %v", batches)
+	}
+
+	count, err := table.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 got %d", count)
+	}
+}
+
+func TestTable_QueryManyElements(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Document](ctx, t, store)
+
+	docs := []Document{
+		{"id": "high", "scores": []int{95, 91}},
+		{"id": "mixed", "scores": []int{40, 99}},
+		{"id": "low", "scores": []int{10, 20}},
+		{"id": "none"},
+	}
+	for _, d := range docs {
+		err := table.Insert(ctx, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ids := func(vals []Document) []string {
+		var ids []string
+		for _, v := range vals {
+			ids = append(ids, v["id"].(string))
+		}
+		return ids
+	}
+
+	vals, err := table.QueryMany(ctx, AnyElement("$.scores", ">", 90))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(vals); !slices.Equal(got, []string{"high", "mixed"}) {
+		t.Errorf("expected [high mixed] got %v", got)
+	}
+
+	vals, err = table.QueryMany(ctx, AllElements("$.scores", ">", 90))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(vals); !slices.Equal(got, []string{"high"}) {
+		t.Errorf("expected [high] got %v", got)
+	}
+
+	err = table.Insert(ctx, Document{"id": "ratios", "scores": []float32{0.1, 0.7}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vals, err = table.QueryMany(ctx, AnyElement("$.scores", "=", float32(0.1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(vals); !slices.Equal(got, []string{"ratios"}) {
+		t.Errorf("expected [ratios] got %v", got)
+	}
+}
+
+func TestTable_QueryPage(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	for i := 1; i <= 12; i++ {
+		name := "match"
+		if i%4 == 0 {
+			name = "other"
+		}
+		err := table.Insert(ctx, Foo{Id: i, Name: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	items, total, err := table.QueryPage(ctx, Equal("$.name", "match"), 4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 9 {
+		t.Errorf("expected 9 got %d", total)
+	}
+	var ids []int
+	for _, item := range items {
+		ids = append(ids, item.Id)
+	}
+	if !slices.Equal(ids, []int{3, 5, 6, 7}) {
+		t.Errorf("expected [3 5 6 7] got %v", ids)
+	}
+
+	items, total, err = table.QueryPage(ctx, Equal("$.name", "match"), 4, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 9 || len(items) != 0 {
+		t.Errorf("expected 0 items of 9 got %d of %d", len(items), total)
+	}
+
+	items, total, err = table.QueryPage(ctx, Equal("$.name", "match"), 0, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 9 || len(items) != 3 {
+		t.Errorf("expected 3 items of 9 got %d of %d", len(items), total)
+	}
+}
+
+func TestTable_QueryManyOrdered(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	for i := 1; i <= 6; i++ {
+		name := "match"
+		if i == 4 {
+			name = "other"
+		}
+		err := table.Insert(ctx, Foo{Id: i, Name: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ids := func(items []Foo) []int {
+		var ids []int
+		for _, item := range items {
+			ids = append(ids, item.Id)
+		}
+		return ids
+	}
+
+	items, err := table.QueryManyOrdered(ctx, Equal("$.name", "match"), []Order{Desc("$.id")}, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(items); !slices.Equal(got, []int{5, 3}) {
+		t.Errorf("expected [5 3] got %v", got)
+	}
+
+	items, err = table.QueryManyOrdered(ctx, Equal("$.name", "match"), []Order{Desc("$.id")}, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(items); !slices.Equal(got, []int{3, 2, 1}) {
+		t.Errorf("expected [3 2 1] got %v", got)
+	}
+
+	_, err = table.QueryManyOrdered(ctx, All(), []Order{Asc("$.id'")}, 1, 0)
+	if !errors.Is(err, ErrInvalidField) {
+		t.Errorf("expected ErrInvalidField got %v", err)
+	}
+}
+
+func TestTable_QueryManyIs(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Document](ctx, t, store)
+
+	docs := []Document{
+		{"id": "set", "v": "x"},
+		{"id": "other", "v": "y"},
+		{"id": "null", "v": nil},
+		{"id": "missing"},
+	}
+	for _, d := range docs {
+		err := table.Insert(ctx, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ids := func(vals []Document) []string {
+		var ids []string
+		for _, v := range vals {
+			ids = append(ids, v["id"].(string))
+		}
+		return ids
+	}
+
+	vals, err := table.QueryMany(ctx, Is("$.v", "x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(vals); !slices.Equal(got, []string{"set"}) {
+		t.Errorf("expected [set] got %v", got)
+	}
+
+	vals, err = table.QueryMany(ctx, Is("$.v", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(vals); !slices.Equal(got, []string{"null", "missing"}) {
+		t.Errorf("expected [null missing] got %v", got)
+	}
+
+	// IN compares with =, which never matches NULL
+	vals, err = table.QueryMany(ctx, In("$.v", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 0 {
+		t.Errorf("expected 0 got %d", len(vals))
+	}
+}
+
+func TestTable_Scoped(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[TenantItem](ctx, t, store)
+
+	for i, tenant := range []string{"a", "b", "a", "b", "a"} {
+		err := table.Insert(ctx, TenantItem{Tenant: tenant, Id: i + 1, Name: "item"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	scoped := table.Scoped(Equal("$.tenant", "a"))
+
+	count, err := scoped.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 got %d", count)
+	}
+
+	// a broad clause cannot escape the scope
+	vals, err := scoped.QueryMany(ctx, Or(Equal("$.tenant", "b"), Equal("$.name", "item")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 3 {
+		t.Errorf("expected 3 got %d", len(vals))
+	}
+	for _, v := range vals {
+		if v.Tenant != "a" {
+			t.Errorf("expected tenant a got %s", v.Tenant)
+		}
+	}
+
+	val, err := scoped.QueryOne(ctx, Equal("$.id", 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val != nil {
+		t.Errorf("expected nil got %v", val)
+	}
+
+	err = scoped.Update(ctx, All(), TenantItem{Tenant: "a", Name: "updated"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = scoped.Delete(ctx, Equal("$.tenant", "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vals, err = table.QueryMany(ctx, Equal("$.tenant", "b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 2 {
+		t.Errorf("expected 2 got %d", len(vals))
+	}
+	for _, v := range vals {
+		if v.Name != "item" {
+			t.Errorf("expected item got %s", v.Name)
+		}
+	}
+}
+
+func TestTable_WithValidator(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	errEmptyName := errors.New("empty name")
+	table := helperTable[Foo](ctx, t, store).WithValidator(func(f Foo) error {
+		if f.Name == "" {
+			return errEmptyName
+		}
+		return nil
+	})
+
+	err := table.Insert(ctx, Foo{Id: 1})
+	if !errors.Is(err, errEmptyName) {
+		t.Errorf("expected %v got %v", errEmptyName, err)
+	}
+
+	err = table.Insert(ctx, Foo{Id: 1, Name: "valid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = table.Update(ctx, Equal("$.id", 1), Foo{Id: 1})
+	if !errors.Is(err, errEmptyName) {
+		t.Errorf("expected %v got %v", errEmptyName, err)
+	}
+
+	_, err = table.UpsertMany(ctx, "id", []Foo{{Id: 2, Name: "valid"}, {Id: 3}})
+	if !errors.Is(err, errEmptyName) {
+		t.Errorf("expected %v got %v", errEmptyName, err)
+	}
+
+	err = table.UpsertByKeys(ctx, []string{"id"}, Foo{Id: 4})
+	if !errors.Is(err, errEmptyName) {
+		t.Errorf("expected %v got %v", errEmptyName, err)
+	}
+
+	vals, err := table.All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 1 || vals[0].Name != "valid" {
+		t.Errorf("expected only the valid document got %v", vals)
+	}
+}
+
+type FooV2 struct {
+	Id    int    `json:"id,omitempty"`
+	Title string `json:"title,omitempty"`
+}
+
+func TestCopyTo(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	src := helperTable[Foo](ctx, t, store)
+	dst := helperTable[FooV2](ctx, t, store)
+
+	for i := 1; i <= 3; i++ {
+		err := src.Insert(ctx, Foo{Id: i, Name: fmt.Sprintf("name-%d", i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	copied, err := CopyTo(ctx, src, dst, func(f Foo) (FooV2, error) {
+		return FooV2{Id: f.Id, Title: f.Name}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copied != 3 {
+		t.Errorf("expected 3 got %d", copied)
+	}
+
+	vals, err := dst.All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []FooV2{{Id: 1, Title: "name-1"}, {Id: 2, Title: "name-2"}, {Id: 3, Title: "name-3"}}
+	if !slices.Equal(vals, expected) {
+		t.Errorf("expected %v got %v", expected, vals)
+	}
+
+	// a failed transform leaves the destination unchanged
+	errTransform := errors.New("transform failed")
+	_, err = CopyTo(ctx, src, dst, func(f Foo) (FooV2, error) {
+		if f.Id == 2 {
+			return FooV2{}, errTransform
+		}
+		return FooV2{Id: f.Id}, nil
+	})
+	if !errors.Is(err, errTransform) {
+		t.Errorf("expected %v got %v", errTransform, err)
+	}
+
+	count, err := dst.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 got %d", count)
+	}
+}
+
+func TestCopyTo_SameTable(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	for i := 1; i <= 3; i++ {
+		err := table.Insert(ctx, Foo{Id: i, Name: fmt.Sprintf("name-%d", i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// items copied into the table are not copied again
+	copied, err := CopyTo(ctx, table, table, func(f Foo) (Foo, error) {
+		return Foo{Id: f.Id + 10, Name: f.Name}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copied != 3 {
+		t.Errorf("expected 3 got %d", copied)
+	}
+
+	count, err := table.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 6 {
+		t.Errorf("expected 6 got %d", count)
+	}
+}
+
+func TestTable_Latest(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	for i := 1; i <= 10; i++ {
+		err := table.Insert(ctx, Foo{Id: i})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vals, err := table.Latest(ctx, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for _, v := range vals {
+		ids = append(ids, v.Id)
+	}
+	if !slices.Equal(ids, []int{10, 9, 8}) {
+		t.Errorf("expected [10 9 8] got %v", ids)
+	}
+
+	vals, err = table.Latest(ctx, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 10 {
+		t.Errorf("expected 10 got %d", len(vals))
+	}
+}
+
+type Labelled struct {
+	Name   string            `json:"name,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+func TestTable_QueryManyMapKey(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Labelled](ctx, t, store)
+
+	items := []Labelled{
+		{Name: "api", Labels: map[string]string{"env": "prod", "team-name": "core"}},
+		{Name: "worker", Labels: map[string]string{"env": "dev", "team-name": "core"}},
+		{Name: "web", Labels: map[string]string{"env": "prod", "team-name": "edge"}},
+		{Name: "unlabelled"},
+	}
+	for _, item := range items {
+		err := table.Insert(ctx, item)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := func(vals []Labelled) []string {
+		var names []string
+		for _, v := range vals {
+			names = append(names, v.Name)
+		}
+		return names
+	}
+
+	vals, err := table.QueryMany(ctx, Equal("$.labels.env", "prod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(vals); !slices.Equal(got, []string{"api", "web"}) {
+		t.Errorf("expected [api web] got %v", got)
+	}
+
+	vals, err = table.QueryMany(ctx, And(Equal("labels.env", "prod"), Equal("labels.team-name", "core")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(vals); !slices.Equal(got, []string{"api"}) {
+		t.Errorf("expected [api] got %v", got)
+	}
+
+	vals, err = table.QueryMany(ctx, HasKey("$.labels.env"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 3 {
+		t.Errorf("expected 3 got %d", len(vals))
+	}
+}
+
+func TestTable_ForEachBatch(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	for i := 1; i <= 12; i++ {
+		name := "match"
+		if i%6 == 0 {
+			name = "other"
+		}
+		err := table.Insert(ctx, Foo{Id: i, Name: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var sizes []int
+	var ids []int
+	err := table.ForEachBatch(ctx, Equal("$.name", "match"), 4, func(batch []Foo) error {
+		sizes = append(sizes, len(batch))
+		for _, f := range batch {
+			ids = append(ids, f.Id)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(sizes, []int{4, 4, 2}) {
+		t.Errorf("expected [4 4 2] got %v", sizes)
+	}
+	if !slices.Equal(ids, []int{1, 2, 3, 4, 5, 7, 8, 9, 10, 11}) {
+		t.Errorf("expected [1 2 3 4 5 7 8 9 10 11] got %v", ids)
+	}
+
+	errStop := errors.New("stop")
+	batches := 0
+	err = table.ForEachBatch(ctx, All(), 5, func(batch []Foo) error {
+		batches++
+		return errStop
+	})
+	if !errors.Is(err, errStop) {
+		t.Errorf("expected %v got %v", errStop, err)
+	}
+	if batches != 1 {
+		t.Errorf("expected 1 got %d", batches)
+	}
+
+	err = table.ForEachBatch(ctx, All(), 0, func(batch []Foo) error { return nil })
+	if err == nil {
+		t.Error("expected error for invalid batch size got nil")
+	}
+}
+
+func TestTable_QueryManyArrayIndex(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	lists := [][]string{{"one", "two"}, {"two", "one"}, {"one"}}
+	for i, list := range lists {
+		err := table.Insert(ctx, Foo{Id: i + 1, List: list})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, field := range []string{"$.list[0]", "list[0]"} {
+		vals, err := table.QueryMany(ctx, Equal(field, "one"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []int
+		for _, v := range vals {
+			ids = append(ids, v.Id)
+		}
+		if !slices.Equal(ids, []int{1, 3}) {
+			t.Errorf("expected [1 3] for %s got %v", field, ids)
+		}
+	}
+
+	// the last element
+	vals, err := table.QueryMany(ctx, Equal("$.list[#-1]", "one"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 2 || vals[0].Id != 2 || vals[1].Id != 3 {
+		t.Errorf("expected ids [2 3] got %v", vals)
+	}
+}
+
+func TestTable_Stream(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	for i := 1; i <= 10; i++ {
+		err := table.Insert(ctx, Foo{Id: i, Name: "streamed"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	items, errs := table.Stream(ctx, GreaterThan("$.id", 5))
+
+	var ids []int
+	for item := range items {
+		ids = append(ids, item.Id)
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ids, []int{6, 7, 8, 9, 10}) {
+		t.Errorf("expected [6 7 8 9 10] got %v", ids)
+	}
+}
+
+func TestTable_StreamCancelled(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	for i := 1; i <= 10; i++ {
+		err := table.Insert(ctx, Foo{Id: i, Name: "streamed"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	items, errs := table.Stream(streamCtx, All())
+
+	for i := 0; i < 2; i++ {
+		<-items
+	}
+	cancel()
+
+	// the goroutine is blocked sending the next item until it sees the cancellation
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v got %v", context.Canceled, err)
+	}
+	if _, ok := <-items; ok {
+		t.Error("expected items to be closed")
+	}
+
+	if inUse := store.Stats().InUse; inUse != 0 {
+		t.Errorf("expected rows to be closed got %d connections in use", inUse)
+	}
+}
+
+func TestTable_RowIDRange(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	minRowid, maxRowid, err := table.RowIDRange(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if minRowid != 0 || maxRowid != 0 {
+		t.Errorf("expected [0 0] got [%d %d]", minRowid, maxRowid)
+	}
+
+	for i := 1; i <= 10; i++ {
+		err := table.Insert(ctx, Foo{Id: i})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err = table.DeleteLimit(ctx, All(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	minRowid, maxRowid, err = table.RowIDRange(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if minRowid != 3 || maxRowid != 10 {
+		t.Errorf("expected [3 10] got [%d %d]", minRowid, maxRowid)
+	}
+}
+
+func TestTable_QueryManyNotContains(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	foos := []Foo{
+		{Name: "contains-one", List: []string{"one", "two", "three"}},
+		{Name: "contains-two", List: []string{"three", "four", "five"}},
+		{Name: "contains-three", List: []string{"two", "three", "four"}},
+	}
+	for _, f := range foos {
+		err := table.Insert(ctx, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := func(vals []Foo) []string {
+		var names []string
+		for _, v := range vals {
+			names = append(names, v.Name)
+		}
+		return names
+	}
+
+	vals, err := table.QueryMany(ctx, NotContainsAll("$.list", "two", "four"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(vals); !slices.Equal(got, []string{"contains-one", "contains-two"}) {
+		t.Errorf("expected [contains-one contains-two] got %v", got)
+	}
+
+	vals, err = table.QueryMany(ctx, NotContainsAny("$.list", "one", "two"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(vals); !slices.Equal(got, []string{"contains-two"}) {
+		t.Errorf("expected [contains-two] got %v", got)
+	}
+
+	// negated clauses combine with others like any clause
+	vals, err = table.QueryMany(ctx, NotContainsAny("$.list", "five").And(Contains("$.list", "four")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := names(vals); !slices.Equal(got, []string{"contains-three"}) {
+		t.Errorf("expected [contains-three] got %v", got)
+	}
+
+	// with no values NotContainsAny matches every item and NotContainsAll matches none
+	vals, err = table.QueryMany(ctx, NotContainsAny[string]("$.list"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 3 {
+		t.Errorf("expected 3 got %d", len(vals))
+	}
+
+	vals, err = table.QueryMany(ctx, NotContainsAll[string]("$.list"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 0 {
+		t.Errorf("expected 0 got %d", len(vals))
+	}
+}
+
+type Sample struct {
+	Id int   `json:"id,omitempty"`
+	Ts int64 `json:"ts"`
+}
+
+func TestTable_QueryManyUnixTime(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Sample](ctx, t, store)
+
+	indexName, err := table.CreateIndex(ctx, "$.ts")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := 1; i <= 6; i++ {
+		err := table.Insert(ctx, Sample{Id: i, Ts: start.Add(time.Duration(i) * 15 * time.Minute).UnixMilli()})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	clause := And(AfterUnix("$.ts", start.Add(30*time.Minute)), BeforeUnix("$.ts", start.Add(75*time.Minute)))
+
+	vals, err := table.QueryMany(ctx, clause)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for _, v := range vals {
+		ids = append(ids, v.Id)
+	}
+	slices.Sort(ids)
+	if !slices.Equal(ids, []int{3, 4}) {
+		t.Errorf("expected [3 4] got %v", ids)
+	}
+
+	plan, err := table.ExplainQueryPlan(ctx, clause)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(strings.Join(plan, "\n"), indexName) {
+		t.Errorf("expected plan to use %s got %v", indexName, plan)
+	}
+}
+
+func TestTable_InsertSeq(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	generate := func(n int) func(yield func(Foo) bool) {
+		return func(yield func(Foo) bool) {
+			for i := 1; i <= n; i++ {
+				if !yield(Foo{Id: i, Name: fmt.Sprintf("name-%d", i)}) {
+					return
+				}
+			}
+		}
+	}
+
+	inserted, err := table.InsertSeq(ctx, generate(5000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inserted != 5000 {
+		t.Errorf("expected 5000 got %d", inserted)
+	}
+
+	count, err := table.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 5000 {
+		t.Errorf("expected 5000 got %d", count)
+	}
+
+	last, err := table.Get(ctx, Equal("$.id", 5000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.Name != "name-5000" {
+		t.Errorf("expected name-5000 got %s", last.Name)
+	}
+
+	// a failing insert stops the sequence and rolls back the items already inserted
+	validated := table.WithValidator(func(f Foo) error {
+		if f.Id == 3 {
+			return errors.New("invalid")
+		}
+		return nil
+	})
+	yielded := 0
+	_, err = validated.InsertSeq(ctx, func(yield func(Foo) bool) {
+		for i := 1; i <= 10; i++ {
+			yielded++
+			if !yield(Foo{Id: i}) {
+				return
+			}
+		}
+	})
+	if err == nil {
+		t.Fatal("expected error inserting invalid item")
+	}
+	if yielded != 3 {
+		t.Errorf("expected 3 items yielded got %d", yielded)
+	}
+
+	count, err = table.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 5000 {
+		t.Errorf("expected 5000 got %d", count)
+	}
+}
+
+func TestTable_InsertWithRowID(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	err := table.InsertWithRowID(ctx, 42, Foo{Id: 1, Name: "restored"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var data string
+	err = store.QueryRowContext(ctx, fmt.Sprintf("SELECT data FROM %s WHERE rowid = ?", table.Name), 42).Scan(&data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data != `{"id":1,"name":"restored","bar":{}}` {
+		t.Errorf("expected restored got %s", data)
+	}
+
+	err = table.InsertWithRowID(ctx, 42, Foo{Id: 2, Name: "duplicate"})
+	if err == nil || !strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		t.Errorf("expected unique constraint error got %v", err)
+	}
+
+	// later inserts continue after the highest rowid
+	err = table.Insert(ctx, Foo{Id: 3, Name: "next"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, last, err := table.RowIDRange(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last != 43 {
+		t.Errorf("expected 43 got %d", last)
+	}
+}
+
+func TestTable_InsertIgnore(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	name, err := table.CreateUniqueIndex(ctx, "$.id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "idx_nosqlite_foo_id_unique" {
+		t.Errorf("expected idx_nosqlite_foo_id_unique got %s", name)
+	}
+
+	err = table.InsertIgnore(ctx, Foo{Id: 1, Name: "first"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = table.InsertIgnore(ctx, Foo{Id: 1, Name: "duplicate"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a plain insert still reports the conflict
+	err = table.Insert(ctx, Foo{Id: 1, Name: "duplicate"})
+	if err == nil {
+		t.Error("expected unique constraint error got nil")
+	}
+
+	vals, err := table.All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 1 || vals[0].Name != "first" {
+		t.Errorf("expected only the first item got %v", vals)
+	}
+}
+
+func TestTable_WithTTL(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Document](ctx, t, store)
+	cache := table.WithTTL(time.Hour)
+
+	err := table.Insert(ctx, Document{"id": "expired", "_nosqlite_expires_at": time.Now().Add(-time.Minute).UnixMilli()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = table.Insert(ctx, Document{"id": "forever"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cache.Insert(ctx, Document{"id": "live"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	live, err := table.Get(ctx, Equal("$.id", "live"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := live["_nosqlite_expires_at"].(float64); !ok {
+		t.Errorf("expected _nosqlite_expires_at to be recorded got %v", live)
+	}
+
+	vals, err := cache.QueryMany(ctx, All())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, v := range vals {
+		ids = append(ids, v["id"].(string))
+	}
+	if !slices.Equal(ids, []string{"forever", "live"}) {
+		t.Errorf("expected [forever live] got %v", ids)
+	}
+
+	count, err := cache.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 got %d", count)
+	}
+
+	removed, err := cache.PurgeExpired(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 {
+		t.Errorf("expected 1 got %d", removed)
+	}
+
+	count, err = table.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 got %d", count)
+	}
+}
+
+type Session struct {
+	Id        string `json:"id"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+func TestTable_WithTTLOwnExpiresAt(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Session](ctx, t, store)
+	cache := table.WithTTL(time.Hour)
+
+	err := cache.Insert(ctx, Session{Id: "a", ExpiresAt: "tomorrow"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vals, err := cache.All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 1 || vals[0].ExpiresAt != "tomorrow" {
+		t.Errorf("expected the item's own expires_at to be kept got %v", vals)
+	}
+}
+
+func TestTable_WithTTLUpsertMany(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Session](ctx, t, store)
+	cache := table.WithTTL(time.Millisecond)
+
+	_, err := cache.UpsertMany(ctx, "$.id", []Session{{Id: "a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = table.Insert(ctx, Session{Id: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cache.UpsertMany(ctx, "$.id", []Session{{Id: "b", ExpiresAt: "updated"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+
+	count, err := cache.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("expected inserted and updated items to expire got %d", count)
+	}
+
+	count, err = table.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 got %d", count)
+	}
+}
+
+func TestTable_WithTTLSetField(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Session](ctx, t, store)
+	cache := table.WithTTL(time.Millisecond)
+
+	for _, id := range []string{"a", "b"} {
+		err := table.Insert(ctx, Session{Id: id, ExpiresAt: "never"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := cache.SetField(ctx, Equal("$.id", "a"), "$.expires_at", "soon")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cache.RemoveField(ctx, Equal("$.id", "b"), "$.expires_at")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+
+	count, err := cache.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("expected changed items to expire got %d", count)
+	}
+
+	vals, err := table.All(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Session{{Id: "a", ExpiresAt: "soon"}, {Id: "b"}}
+	if !slices.Equal(vals, expected) {
+		t.Errorf("expected %v got %v", expected, vals)
+	}
+}
+
+type Account struct {
+	Name   string     `json:"name,omitempty"`
+	Status testStatus `json:"status"`
+}
+
+func TestTable_QueryManyEnum(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Account](ctx, t, store)
+
+	for _, a := range []Account{{Name: "one", Status: testStatusActive}, {Name: "two", Status: testStatusInactive}} {
+		err := table.Insert(ctx, a)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vals, err := table.QueryMany(ctx, Equal("$.status", testStatusActive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 1 || vals[0].Name != "one" {
+		t.Errorf("expected [one] got %v", vals)
+	}
+
+	vals, err = table.QueryMany(ctx, In("$.status", testStatusInactive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 1 || vals[0].Name != "two" {
+		t.Errorf("expected [two] got %v", vals)
+	}
+}
+
+func TestTable_QueryUnion(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	for i, name := range []string{"one", "two", "three", "four"} {
+		err := table.Insert(ctx, Foo{Id: i + 1, Name: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vals, err := table.QueryUnion(ctx, LessThanOrEqual("$.id", 2), AnyOf("$.name", "two", "three"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for _, v := range vals {
+		ids = append(ids, v.Id)
+	}
+	slices.Sort(ids)
+	if !slices.Equal(ids, []int{1, 2, 3}) {
+		t.Errorf("expected [1 2 3] got %v", ids)
+	}
+
+	vals, err = table.QueryUnion(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 0 {
+		t.Errorf("expected no items got %v", vals)
+	}
+
+	_, err = table.QueryUnion(ctx, All(), Equal("$.name'", "x"))
+	if !errors.Is(err, ErrInvalidField) {
+		t.Errorf("expected ErrInvalidField got %v", err)
+	}
+}
+
+func TestTable_QueryManyTimed(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	for i := 1; i <= 3; i++ {
+		err := table.Insert(ctx, Foo{Id: i, Name: "timed"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	vals, elapsed, err := table.QueryManyTimed(ctx, Equal("$.name", "timed"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vals) != 3 {
+		t.Errorf("expected 3 got %d", len(vals))
+	}
+	if elapsed <= 0 {
+		t.Errorf("expected a positive duration got %v", elapsed)
+	}
+}
+
+type Cached struct {
+	Key       string `json:"key"`
+	Value     string `json:"value"`
+	ExpiresAt int64  `json:"_nosqlite_expires_at,omitempty"`
+}
+
+func TestTable_UpsertReturning(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Cached](ctx, t, store)
+	cache := table.WithTTL(time.Hour)
+
+	before := time.Now().UnixMilli()
+
+	saved, err := cache.UpsertReturning(ctx, "$.key", Cached{Key: "a", Value: "one"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Value != "one" || saved.ExpiresAt < before+time.Hour.Milliseconds() {
+		t.Errorf("expected one expiring in an hour got %v", saved)
+	}
+
+	saved, err = cache.UpsertReturning(ctx, "$.key", Cached{Key: "a", Value: "two"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Value != "two" || saved.ExpiresAt == 0 {
+		t.Errorf("expected two with an expiry got %v", saved)
+	}
+
+	count, err := table.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 got %d", count)
+	}
+
+	_, err = table.UpsertReturning(ctx, "$.missing", Cached{Key: "b"})
+	if err == nil {
+		t.Error("expected error for missing key got nil")
+	}
+}
@@ -0,0 +1,119 @@
+package nosqlite
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// Result holds the outcome of a write recorded by a TrackingTable
+type Result struct {
+	rowsAffected int64
+	lastInsertID int64
+}
+
+// RowsAffected returns the number of items the write inserted, changed or removed
+func (r Result) RowsAffected() int64 {
+	return r.rowsAffected
+}
+
+// LastInsertId returns the rowid of the most recently inserted item as of the write
+func (r Result) LastInsertId() int64 {
+	return r.lastInsertID
+}
+
+// TrackingTable is a view of a table recording the result of its most recent successful
+// Insert, Update or Delete, for callers needing the number of items affected without the
+// TableAPI methods returning it. Other operations are passed through to the table untracked.
+type TrackingTable[T any] struct {
+	*Table[T]
+
+	mu   sync.Mutex
+	last Result
+}
+
+// Tracking returns a view of the table recording the result of each write
+func (n *Table[T]) Tracking() *TrackingTable[T] {
+	return &TrackingTable[T]{Table: n}
+}
+
+// LastResult returns the result of the most recent successful write through the view, or
+// a zero Result if there has been none
+func (t *TrackingTable[T]) LastResult() Result {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.last
+}
+
+// Insert adds a new item to the table
+func (t *TrackingTable[T]) Insert(ctx context.Context, data T) error {
+	ctx, cancel := t.operationContext(ctx)
+	defer cancel()
+
+	return t.track(ctx, func(db executor) error {
+		return t.insert(ctx, db, data)
+	})
+}
+
+// Update changes one or more items in the table
+func (t *TrackingTable[T]) Update(ctx context.Context, clause Clause, newVal T) error {
+	ctx, cancel := t.operationContext(ctx)
+	defer cancel()
+
+	return t.track(ctx, func(db executor) error {
+		_, err := t.update(ctx, db, clause, newVal)
+		return err
+	})
+}
+
+// Delete removes items from the table that match the given clause
+func (t *TrackingTable[T]) Delete(ctx context.Context, clause Clause) error {
+	ctx, cancel := t.operationContext(ctx)
+	defer cancel()
+
+	return t.track(ctx, func(db executor) error {
+		_, err := t.delete(ctx, db, clause)
+		return err
+	})
+}
+
+// track runs write against the store, retrying when busy, and records its result if it succeeds
+func (t *TrackingTable[T]) track(ctx context.Context, write func(db executor) error) error {
+	var res sql.Result
+	err := t.store.withRetry(ctx, func() error {
+		return write(resultExecutor{executor: t.store, result: &res})
+	})
+	if err != nil || res == nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	lastInsertID, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.last = Result{rowsAffected: rowsAffected, lastInsertID: lastInsertID}
+	return nil
+}
+
+// resultExecutor is an executor keeping the result of the last statement it executes
+type resultExecutor struct {
+	executor
+	result *sql.Result
+}
+
+func (e resultExecutor) execContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	res, err := e.executor.execContext(ctx, query, args...)
+	if err == nil {
+		*e.result = res
+	}
+	return res, err
+}
+
+var _ TableAPI[any] = (*TrackingTable[any])(nil)
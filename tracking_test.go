@@ -0,0 +1,62 @@
+package nosqlite
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTrackingTable_LastResult(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store).Tracking()
+
+	if got := table.LastResult(); got != (Result{}) {
+		t.Errorf("got = %v, want %v", got, Result{})
+	}
+
+	for i, name := range []string{"dup", "dup", "unique"} {
+		err := table.Insert(ctx, Foo{Id: i + 1, Name: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := table.LastResult(); got.RowsAffected() != 1 || got.LastInsertId() != 3 {
+		t.Errorf("expected 1 row affected and last insert id 3 got %d and %d", got.RowsAffected(), got.LastInsertId())
+	}
+
+	err := table.Update(ctx, Equal("$.name", "dup"), Foo{Name: "updated"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := table.LastResult().RowsAffected(); got != 2 {
+		t.Errorf("expected 2 got %d", got)
+	}
+
+	err = table.Delete(ctx, Equal("$.name", "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := table.LastResult().RowsAffected(); got != 0 {
+		t.Errorf("expected 0 got %d", got)
+	}
+
+	// a failed write leaves the last result unchanged
+	err = table.Delete(ctx, Equal("$.name'", "unique"))
+	if err == nil {
+		t.Fatal("expected error deleting with an invalid field")
+	}
+	if got := table.LastResult().RowsAffected(); got != 0 {
+		t.Errorf("expected 0 got %d", got)
+	}
+
+	err = table.Delete(ctx, Equal("$.name", "unique"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := table.LastResult().RowsAffected(); got != 1 {
+		t.Errorf("expected 1 got %d", got)
+	}
+}
@@ -0,0 +1,291 @@
+package nosqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// Transaction represents a transaction on the store
+type Transaction struct {
+	store *Store
+	tx    sqlTx
+
+	// unlock releases the store's write lock, held for the life of the transaction when
+	// writes are serialized
+	unlock func()
+}
+
+// sqlTx is satisfied by *sql.Tx and connTx, whose rows Transaction wraps in Row
+type sqlTx interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	Commit() error
+	Rollback() error
+}
+
+// BeginTx starts a new transaction
+func (s *Store) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Transaction, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+
+	unlock := func() {}
+	if opts == nil || !opts.ReadOnly {
+		var err error
+		unlock, err = s.lockWrites(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, opts)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	return &Transaction{store: s, tx: tx, unlock: unlock}, nil
+}
+
+// BeginImmediate starts a new transaction that acquires the database write lock up front,
+// waiting for other writers to finish rather than failing with SQLITE_BUSY part way through
+func (s *Store) BeginImmediate(ctx context.Context) (*Transaction, error) {
+	return s.beginConnTx(ctx, "BEGIN IMMEDIATE")
+}
+
+// BeginExclusive starts a new transaction that acquires the database write lock up front.
+// In WAL mode this behaves as BeginImmediate, otherwise it also prevents other connections
+// from reading until the transaction ends.
+func (s *Store) BeginExclusive(ctx context.Context) (*Transaction, error) {
+	return s.beginConnTx(ctx, "BEGIN EXCLUSIVE")
+}
+
+// beginConnTx starts a transaction with begin on a dedicated connection, as sql.TxOptions
+// cannot express SQLite's transaction types
+func (s *Store) beginConnTx(ctx context.Context, begin string) (*Transaction, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+
+	unlock, err := s.lockWrites(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+
+	_, err = conn.ExecContext(ctx, begin)
+	if err != nil {
+		unlock()
+		return nil, errors.Join(err, conn.Close())
+	}
+	return &Transaction{store: s, tx: &connTx{Conn: conn}, unlock: unlock}, nil
+}
+
+// connTx is a transaction managed with explicit statements on a dedicated connection,
+// which is returned to the pool when the transaction ends
+type connTx struct {
+	*sql.Conn
+	done bool
+}
+
+func (c *connTx) end(statement string) error {
+	if c.done {
+		return sql.ErrTxDone
+	}
+	c.done = true
+
+	_, err := c.ExecContext(context.Background(), statement)
+	if err != nil && statement != "ROLLBACK" {
+		// leave the connection clean for its next user
+		_, _ = c.ExecContext(context.Background(), "ROLLBACK")
+	}
+	return errors.Join(err, c.Close())
+}
+
+func (c *connTx) Commit() error {
+	return c.end("COMMIT")
+}
+
+func (c *connTx) Rollback() error {
+	return c.end("ROLLBACK")
+}
+
+// WithTx runs fn in a new transaction, committing if fn returns nil and rolling back otherwise
+func (s *Store) WithTx(ctx context.Context, fn func(*Transaction) error) error {
+	tx, err := s.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	err = fn(tx)
+	if err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+
+	return tx.Commit()
+}
+
+// Setup runs fn in a new transaction that holds the write lock from the start, committing if fn
+// returns nil and rolling back otherwise. SQLite schema changes are transactional, so tables
+// created in fn with NewTableTx, and indexes created with TableWithTx.CreateIndex, are all
+// created or, if any step fails, none are.
+func (s *Store) Setup(ctx context.Context, fn func(*Transaction) error) error {
+	tx, err := s.BeginImmediate(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = fn(tx)
+	if err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+
+	return tx.Commit()
+}
+
+// WithTxMaybe runs fn in tx if it is non-nil, leaving the caller responsible for committing it.
+// Otherwise fn is run in a new transaction managed as in WithTx.
+func (s *Store) WithTxMaybe(ctx context.Context, tx *Transaction, fn func(*Transaction) error) error {
+	if tx != nil {
+		return fn(tx)
+	}
+	return s.WithTx(ctx, fn)
+}
+
+// Commit commits the transaction
+func (t *Transaction) Commit() error {
+	defer t.release()
+	return t.tx.Commit()
+}
+
+// Rollback aborts the transaction
+func (t *Transaction) Rollback() error {
+	defer t.release()
+	return t.tx.Rollback()
+}
+
+// release releases the store's write lock the first time the transaction ends
+func (t *Transaction) release() {
+	if t.unlock != nil {
+		t.unlock()
+		t.unlock = nil
+	}
+}
+
+// ExecContext executes a query in the transaction without returning any rows
+func (t *Transaction) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return t.execContext(ctx, query, args...)
+}
+
+// QueryContext executes a query in the transaction that returns rows
+func (t *Transaction) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return t.queryContext(ctx, query, args...)
+}
+
+// QueryRowContext executes a query in the transaction that returns at most one row
+func (t *Transaction) QueryRowContext(ctx context.Context, query string, args ...any) *Row {
+	return t.queryRowContext(ctx, query, args...)
+}
+
+func (t *Transaction) execContext(ctx context.Context, query string, args ...any) (res sql.Result, err error) {
+	defer func(start time.Time) { t.store.observe(ctx, query, args, start, err) }(time.Now())
+
+	return t.tx.ExecContext(ctx, query, args...)
+}
+
+func (t *Transaction) queryContext(ctx context.Context, query string, args ...any) (rows *sql.Rows, err error) {
+	defer func(start time.Time) { t.store.observe(ctx, query, args, start, err) }(time.Now())
+
+	return t.tx.QueryContext(ctx, query, args...)
+}
+
+func (t *Transaction) queryRowContext(ctx context.Context, query string, args ...any) (r *Row) {
+	defer func(start time.Time) { t.store.observe(ctx, query, args, start, r.Err()) }(time.Now())
+
+	return &Row{Row: t.tx.QueryRowContext(ctx, query, args...)}
+}
+
+// TableWithTx is a view of a table whose operations run within a transaction
+type TableWithTx[T any] struct {
+	table *Table[T]
+	tx    *Transaction
+
+	// Name of the table
+	Name string
+}
+
+// WithTransaction returns a view of the table whose operations run within tx
+func (n *Table[T]) WithTransaction(tx *Transaction) *TableWithTx[T] {
+	return &TableWithTx[T]{table: n, tx: tx, Name: n.Name}
+}
+
+// Count returns the number of items in the table
+func (t *TableWithTx[T]) Count(ctx context.Context) (uint64, error) {
+	return t.table.count(ctx, t.tx)
+}
+
+// CountWhere returns the number of items in the table matching clause, including those
+// written earlier in the transaction
+func (t *TableWithTx[T]) CountWhere(ctx context.Context, clause Clause) (uint64, error) {
+	return t.table.countWhere(ctx, t.tx, t.table.scoped(clause))
+}
+
+// CreateIndex creates an index on the given fields within the transaction
+func (t *TableWithTx[T]) CreateIndex(ctx context.Context, fields ...string) (string, error) {
+	return t.table.createIndex(ctx, t.tx, false, fields...)
+}
+
+// Delete removes items from the table that match the given clause
+func (t *TableWithTx[T]) Delete(ctx context.Context, clause Clause) error {
+	_, err := t.table.delete(ctx, t.tx, clause)
+	return err
+}
+
+// Insert adds a new item to the table
+func (t *TableWithTx[T]) Insert(ctx context.Context, data T) error {
+	return t.table.insert(ctx, t.tx, data)
+}
+
+// QueryOne returns a single item from the table
+func (t *TableWithTx[T]) QueryOne(ctx context.Context, clause Clause) (*T, error) {
+	return t.table.queryOne(ctx, t.tx, clause)
+}
+
+// Get returns a single item from the table, or ErrNotFound if no item matches clause
+func (t *TableWithTx[T]) Get(ctx context.Context, clause Clause) (T, error) {
+	return t.table.get(ctx, t.tx, clause)
+}
+
+// QueryFirst returns the first item from the table matching clause after applying order
+func (t *TableWithTx[T]) QueryFirst(ctx context.Context, clause Clause, order ...Order) (*T, error) {
+	return t.table.queryFirst(ctx, t.tx, clause, order...)
+}
+
+// All returns every item in the table
+func (t *TableWithTx[T]) All(ctx context.Context) ([]T, error) {
+	return t.QueryMany(ctx, All())
+}
+
+// QueryMany returns multiple items from the table
+func (t *TableWithTx[T]) QueryMany(ctx context.Context, clause Clause) ([]T, error) {
+	return t.table.queryMany(ctx, t.tx, clause)
+}
+
+// QueryManyOrdered returns up to limit items matching clause sorted by orders, after skipping
+// offset of them. A limit of 0 returns every item after offset.
+func (t *TableWithTx[T]) QueryManyOrdered(ctx context.Context, clause Clause, orders []Order, limit, offset uint64) ([]T, error) {
+	return t.table.queryManyOrdered(ctx, t.tx, clause, orders, limit, offset)
+}
+
+// Update changes one or more items in the table
+func (t *TableWithTx[T]) Update(ctx context.Context, clause Clause, newVal T) error {
+	_, err := t.table.update(ctx, t.tx, clause, newVal)
+	return err
+}
@@ -0,0 +1,455 @@
+package nosqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTableWithTx_Commit(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	tx, err := store.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = table.WithTransaction(tx).Insert(ctx, Foo{Name: "tx-commit"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	val, err := table.QueryOne(ctx, Equal("$.name", "tx-commit"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if val == nil {
+		t.Fatal("expected result got nil")
+	}
+}
+
+func TestTableWithTx_Rollback(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	tx, err := store.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txTable := table.WithTransaction(tx)
+
+	err = txTable.Insert(ctx, Foo{Name: "tx-rollback"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := txTable.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 got %d", count)
+	}
+
+	err = tx.Rollback()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err = table.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("expected 0 got %d", count)
+	}
+}
+
+func TestStore_WithTxMaybeSelfManaged(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	err := store.WithTxMaybe(ctx, nil, func(tx *Transaction) error {
+		return table.WithTransaction(tx).Insert(ctx, Foo{Name: "self-managed"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := table.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 got %d", count)
+	}
+
+	errFailed := errors.New("failed")
+	err = store.WithTxMaybe(ctx, nil, func(tx *Transaction) error {
+		err := table.WithTransaction(tx).Insert(ctx, Foo{Name: "rolled-back"})
+		if err != nil {
+			return err
+		}
+		return errFailed
+	})
+	if !errors.Is(err, errFailed) {
+		t.Fatalf("expected %v got %v", errFailed, err)
+	}
+
+	count, err = table.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 got %d", count)
+	}
+}
+
+func TestStore_WithTxMaybePassedIn(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	tx, err := store.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.WithTxMaybe(ctx, tx, func(inner *Transaction) error {
+		if inner != tx {
+			t.Error("expected the supplied transaction to be reused")
+		}
+		return table.WithTransaction(inner).Insert(ctx, Foo{Name: "passed-in"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the supplied transaction must still be open
+	count, err := table.WithTransaction(tx).Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 got %d", count)
+	}
+
+	err = tx.Rollback()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count, err = table.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("expected 0 got %d", count)
+	}
+}
+
+func TestStore_BeginImmediate(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	for name, begin := range map[string]func(context.Context) (*Transaction, error){
+		"immediate": store.BeginImmediate,
+		"exclusive": store.BeginExclusive,
+	} {
+		tx, err := begin(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the write lock is held before the transaction writes anything
+		done := make(chan error, 1)
+		go func() {
+			done <- table.Insert(ctx, Foo{Name: name + "-second"})
+		}()
+
+		select {
+		case err = <-done:
+			t.Fatalf("%s: expected second writer to be blocked got %v", name, err)
+		case <-time.After(200 * time.Millisecond):
+		}
+
+		err = table.WithTransaction(tx).Insert(ctx, Foo{Name: name + "-first"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = <-done
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err = tx.Rollback(); !errors.Is(err, sql.ErrTxDone) {
+			t.Errorf("%s: expected %v got %v", name, sql.ErrTxDone, err)
+		}
+	}
+
+	count, err := table.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 4 {
+		t.Errorf("expected 4 got %d", count)
+	}
+}
+
+func TestTableWithTx_CountWhere(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	tx, err := store.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	txTable := table.WithTransaction(tx)
+	for i, name := range []string{"dup", "dup", "unique"} {
+		err = txTable.Insert(ctx, Foo{Id: i + 1, Name: name})
+		if err != nil {
+			_ = tx.Rollback()
+			t.Fatal(err)
+		}
+	}
+
+	count, err := txTable.CountWhere(ctx, Equal("$.name", "dup"))
+	if err != nil {
+		_ = tx.Rollback()
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 got %d", count)
+	}
+
+	count, err = table.WithTransaction(tx).CountWhere(ctx, Equal("$.name", "missing"))
+	if err != nil {
+		_ = tx.Rollback()
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("expected 0 got %d", count)
+	}
+
+	_, err = txTable.CountWhere(ctx, Equal("$.name'", "dup"))
+	if !errors.Is(err, ErrInvalidField) {
+		t.Errorf("expected ErrInvalidField got %v", err)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTableWithTx_QueryManyOrdered(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	table := helperTable[Foo](ctx, t, store)
+
+	tx, err := store.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	txTable := table.WithTransaction(tx)
+	for i := 1; i <= 5; i++ {
+		err = txTable.Insert(ctx, Foo{Id: i, Name: fmt.Sprintf("name-%d", i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// an offset without a limit returns every item after offset
+	items, err := txTable.QueryManyOrdered(ctx, All(), []Order{Desc("$.id")}, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]int, len(items))
+	for i, item := range items {
+		ids[i] = item.Id
+	}
+	if want := []int{3, 2, 1}; !slices.Equal(ids, want) {
+		t.Errorf("got = %v, want %v", ids, want)
+	}
+
+	items, err = txTable.QueryManyOrdered(ctx, All(), []Order{Asc("$.id")}, 2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Id != 2 || items[1].Id != 3 {
+		t.Errorf("expected items 2 and 3 got %v", items)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStore_Setup(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	schemaNames := func() []string {
+		t.Helper()
+		rows, err := store.db.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type IN ('table', 'index') ORDER BY name")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+
+		var names []string
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				t.Fatal(err)
+			}
+			names = append(names, name)
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return names
+	}
+
+	setupErr := errors.New("setup failed")
+	err := store.Setup(ctx, func(tx *Transaction) error {
+		accounts, err := NewTableTx[Account](ctx, tx)
+		if err != nil {
+			return err
+		}
+		_, err = accounts.WithTransaction(tx).CreateIndex(ctx, "$.name")
+		if err != nil {
+			return err
+		}
+		_, err = NewTableTx[Sample](ctx, tx)
+		if err != nil {
+			return err
+		}
+		return setupErr
+	})
+	if !errors.Is(err, setupErr) {
+		t.Errorf("expected %v got %v", setupErr, err)
+	}
+	if names := schemaNames(); len(names) != 0 {
+		t.Errorf("expected no tables or indexes got %v", names)
+	}
+
+	var indexName string
+	err = store.Setup(ctx, func(tx *Transaction) error {
+		accounts, err := NewTableTx[Account](ctx, tx)
+		if err != nil {
+			return err
+		}
+		indexName, err = accounts.WithTransaction(tx).CreateIndex(ctx, "$.name")
+		if err != nil {
+			return err
+		}
+		return accounts.WithTransaction(tx).Insert(ctx, Account{Name: "first"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names, want := schemaNames(), []string{indexName, tableName[Account]()}; !slices.Equal(names, want) {
+		t.Errorf("got = %v, want %v", names, want)
+	}
+
+	accounts, err := NewTable[Account](ctx, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, err := accounts.Count(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 got %d", count)
+	}
+}
+
+func TestTransaction_QueryRowContext(t *testing.T) {
+	ctx := context.Background()
+
+	store := helperOpenStore(t)
+	defer helperCloseStore(t, store)
+
+	tx, err := store.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = tx.ExecContext(ctx, "PRAGMA user_version = 3")
+	if err != nil {
+		_ = tx.Rollback()
+		t.Fatal(err)
+	}
+
+	var row *Row = tx.QueryRowContext(ctx, "PRAGMA user_version")
+	var version int
+	err = row.Scan(&version)
+	if err != nil {
+		_ = tx.Rollback()
+		t.Fatal(err)
+	}
+	if version != 3 {
+		t.Errorf("expected 3 got %d", version)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = tx.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version)
+	if !errors.Is(err, sql.ErrTxDone) {
+		t.Errorf("expected %v got %v", sql.ErrTxDone, err)
+	}
+}